@@ -0,0 +1,619 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"muxyard/internal/config"
+	"muxyard/internal/frecency"
+	"muxyard/internal/git"
+	"muxyard/internal/snapshot"
+	"muxyard/internal/tmux"
+	"muxyard/internal/zoxide"
+)
+
+// Exit codes returned by the non-interactive subcommands.
+const (
+	exitOK       = 0
+	exitError    = 1
+	exitUsage    = 2
+	exitNotFound = 3
+)
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(cmd *command, app *app, args []string) int
+}
+
+// app carries what every subcommand needs: the loaded config and a client
+// for the tmux server selected by the config or the --socket flags, followed
+// in servers by those for the additional configured servers.
+type app struct {
+	cfg     *config.Config
+	client  *tmux.Client
+	servers []*tmux.Client
+}
+
+var commands = []command{
+	{"ls", "ls [--format fmt]", "List tmux sessions on every configured server", runList},
+	{"repos", "repos [--format fmt]", "List repositories in the configured directories", runRepos},
+	{"new", "new [--template name] [--path dir] [--name name] [--param key=value] [--no-project-config] [--detach]", "Create a session from a template", runNew},
+	{"attach", "attach <name>", "Attach or switch to a session", runAttach},
+	{"kill", "kill <name...>", "Kill one or more sessions", runKill},
+	{"rename", "rename <old> <new>", "Rename a session", runRename},
+	{"save", "save [--commands]", "Save all sessions to the state file", runSave},
+	{"restore", "restore [name...]", "Recreate saved sessions that aren't running", runRestore},
+	{"config", "config validate|migrate [file...]", "Check the config files, or upgrade them to the current version", runConfig},
+}
+
+// offline commands don't talk to tmux, so they work without it installed.
+var offline = map[string]bool{"repos": true, "config": true}
+
+var errSessionNotFound = errors.New("session not found")
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+func runCommand(cfg *config.Config, name string, args []string) int {
+	cmd := findCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "muxyard: unknown command %q\n\n", name)
+		printUsage(os.Stderr)
+		return exitUsage
+	}
+
+	if cmd.name != "config" {
+		for _, issue := range cfg.Issues {
+			fmt.Fprintf(os.Stderr, "muxyard: warning: %s\n", issue)
+		}
+	}
+
+	if !offline[cmd.name] && !tmux.IsTmuxAvailable() {
+		fmt.Fprintln(os.Stderr, "muxyard: tmux is not installed or not found in PATH")
+		return exitError
+	}
+
+	servers := tmux.NewClients(cfg.Tmux)
+	return cmd.run(cmd, &app{cfg: cfg, client: servers[0], servers: servers}, args)
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Muxyard - Tmux Session Manager")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Usage:")
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  muxyard\tStart the interactive TUI")
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  muxyard %s\t%s\n", cmd.usage, cmd.summary)
+	}
+	fmt.Fprintln(tw, "  muxyard --socket-name name ...\tUse the tmux server with this socket name (tmux -L)")
+	fmt.Fprintln(tw, "  muxyard --socket path ...\tUse the tmux server at this socket path (tmux -S)")
+	fmt.Fprintln(tw, "  muxyard --version\tShow version information")
+	fmt.Fprintln(tw, "  muxyard --help\tShow this help message")
+	tw.Flush()
+
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Exit codes: 0 success, 1 error, 2 usage error, 3 session not found")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "For more information, visit: https://github.com/rubenhesselink/muxyard")
+}
+
+func (c *command) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("muxyard "+c.name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: muxyard %s\n", c.usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func fail(err error) int {
+	fmt.Fprintf(os.Stderr, "muxyard: %v\n", err)
+	if errors.Is(err, errSessionNotFound) {
+		return exitNotFound
+	}
+	return exitError
+}
+
+func requireSession(client *tmux.Client, name string) error {
+	exists, err := client.SessionExists(name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s", errSessionNotFound, name)
+	}
+	return nil
+}
+
+// recordVisit adds a use of a session on the client's server to the usage
+// history that orders the TUI's lists. Failing to is only worth a warning.
+func recordVisit(client *tmux.Client, session, path string) {
+	if err := frecency.Record(client.Server, session, path); err != nil {
+		fmt.Fprintf(os.Stderr, "muxyard: failed to record usage: %v\n", err)
+	}
+}
+
+func runList(cmd *command, app *app, args []string) int {
+	fs := cmd.flagSet()
+	format := fs.String("format", "text", formatUsage)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	// List what the servers that answered have, and fail for the others
+	code := exitOK
+	var sessions []tmux.Session
+	for _, server := range app.servers {
+		serverSessions, err := server.ListSessions()
+		if err != nil {
+			code = fail(fmt.Errorf("failed to list sessions on %s: %w", server.Server, err))
+			continue
+		}
+		sessions = append(sessions, serverSessions...)
+	}
+
+	if err := writeRecords(os.Stdout, *format, sessionRecords(sessions, len(app.servers) > 1)); err != nil {
+		return fail(err)
+	}
+	return code
+}
+
+func runRepos(cmd *command, app *app, args []string) int {
+	fs := cmd.flagSet()
+	format := fs.String("format", "text", formatUsage)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	repos, err := git.FindRepositories(app.cfg.RepoDirectories, app.cfg.Markers())
+	if err != nil {
+		return fail(fmt.Errorf("failed to find repositories: %w", err))
+	}
+	if app.cfg.Zoxide.On() {
+		entries, err := zoxide.Query()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "muxyard: warning: failed to read zoxide directories: %v\n", err)
+		} else {
+			repos = zoxide.Merge(repos, entries, app.cfg.Zoxide.MinScore)
+		}
+	}
+
+	if err := writeRecords(os.Stdout, *format, repoRecords(repos)); err != nil {
+		return fail(err)
+	}
+	return exitOK
+}
+
+func runNew(cmd *command, app *app, args []string) int {
+	fs := cmd.flagSet()
+	templateName := fs.String("template", "", "Template to use (defaults to the directory's "+config.ProjectFile+", then the first configured template)")
+	path := fs.String("path", "", "Working directory for the session (defaults to the current directory)")
+	name := fs.String("name", "", "Session name (defaults to the directory name)")
+	detach := fs.Bool("detach", false, "Create the session without attaching to it")
+	noProject := fs.Bool("no-project-config", false, "Ignore the directory's "+config.ProjectFile+", e.g. in a repository that isn't trusted")
+	params := paramFlag{}
+	fs.Var(params, "param", "Template parameter as `key=value` (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	sessionPath := expandPath(*path)
+	if sessionPath == "" {
+		sessionPath = getWorkingDir()
+	}
+	if info, err := os.Stat(sessionPath); err != nil || !info.IsDir() {
+		return fail(fmt.Errorf("directory does not exist: %s", sessionPath))
+	}
+
+	template, err := selectTemplate(app.cfg, *templateName, sessionPath, !*noProject)
+	if err != nil {
+		return fail(err)
+	}
+
+	sessions, err := app.client.ListSessions()
+	if err != nil {
+		return fail(err)
+	}
+
+	sessionName := *name
+	if sessionName == "" {
+		baseName := filepath.Base(sessionPath)
+		if repo, ok := git.RepositoryAt(sessionPath); ok {
+			baseName = repo.SessionName()
+		}
+		sessionName = tmux.UniqueSessionName(baseName, sessions)
+	} else {
+		for _, session := range sessions {
+			if session.Name == sessionName {
+				return fail(fmt.Errorf("session %q already exists", sessionName))
+			}
+		}
+	}
+
+	if err := template.CheckParams(params); err != nil {
+		return fail(fmt.Errorf("%w; pass values as --param name=VALUE", err))
+	}
+	branch, _ := git.CurrentBranch(sessionPath)
+	template = template.Expand(template.Vars(config.BuiltinVars(sessionName, sessionPath, branch), params))
+
+	if template.Source != "" {
+		// A project file comes with the repository, which may not be
+		// trusted; say what it runs
+		reportProjectTemplate(os.Stderr, template)
+	}
+
+	if err := app.client.CreateSession(sessionName, sessionPath, template); err != nil {
+		return fail(err)
+	}
+	recordVisit(app.client, sessionName, sessionPath)
+
+	if *detach {
+		fmt.Println(sessionName)
+		return exitOK
+	}
+
+	if err := app.client.AttachToSession(sessionName); err != nil {
+		return fail(fmt.Errorf("failed to attach to session: %w", err))
+	}
+	return exitOK
+}
+
+// paramFlag collects repeated --param key=value flags.
+type paramFlag map[string]string
+
+func (p paramFlag) String() string {
+	return ""
+}
+
+func (p paramFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	p[key] = val
+	return nil
+}
+
+// selectTemplate picks the named template, or else the project template in
+// dir if allowed, or else the first configured template.
+func selectTemplate(cfg *config.Config, name, dir string, project bool) (*config.SessionTemplate, error) {
+	if name != "" {
+		return cfg.GetTemplate(name)
+	}
+	if project {
+		template, issues, err := cfg.ProjectTemplate(dir)
+		for _, issue := range issues {
+			fmt.Fprintf(os.Stderr, "muxyard: warning: %s\n", issue)
+		}
+		if err != nil || template != nil {
+			return template, err
+		}
+		if len(issues) > 0 {
+			return nil, fmt.Errorf("can't use the project template in %s (--no-project-config to ignore it)", dir)
+		}
+	}
+	if len(cfg.Templates) == 0 {
+		return nil, fmt.Errorf("no templates configured")
+	}
+	return &cfg.Templates[0], nil
+}
+
+// reportProjectTemplate names the project file a session is created from
+// and lists the commands it runs.
+func reportProjectTemplate(w io.Writer, template *config.SessionTemplate) {
+	fmt.Fprintf(w, "muxyard: using project template from %s (--no-project-config to ignore it)\n", template.Source)
+	for _, window := range template.Windows {
+		commands := []string{window.Command}
+		if len(window.Panes) > 0 {
+			commands = commands[:0]
+			for _, pane := range window.Panes {
+				commands = append(commands, pane.Command)
+			}
+		}
+		for _, command := range commands {
+			if command != "" {
+				fmt.Fprintf(w, "  %s: %s\n", window.Name, command)
+			}
+		}
+	}
+}
+
+func runAttach(cmd *command, app *app, args []string) int {
+	fs := cmd.flagSet()
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	name := fs.Arg(0)
+	if err := requireSession(app.client, name); err != nil {
+		return fail(err)
+	}
+
+	recordVisit(app.client, name, "")
+	if err := app.client.AttachToSession(name); err != nil {
+		return fail(fmt.Errorf("failed to attach: %w", err))
+	}
+	return exitOK
+}
+
+func runKill(cmd *command, app *app, args []string) int {
+	fs := cmd.flagSet()
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	// Keep going after a failure so one bad name doesn't leave the rest
+	// running; the exit code reflects the worst failure.
+	code := exitOK
+	for _, name := range fs.Args() {
+		err := requireSession(app.client, name)
+		if err == nil {
+			if err = app.client.KillSession(name); err != nil {
+				err = fmt.Errorf("failed to kill session %s: %w", name, err)
+			}
+		}
+		if err != nil {
+			if c := fail(err); c == exitError || code == exitOK {
+				code = c
+			}
+		}
+	}
+	return code
+}
+
+func runRename(cmd *command, app *app, args []string) int {
+	fs := cmd.flagSet()
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	oldName, newName := fs.Arg(0), fs.Arg(1)
+	if err := requireSession(app.client, oldName); err != nil {
+		return fail(err)
+	}
+
+	exists, err := app.client.SessionExists(newName)
+	if err != nil {
+		return fail(err)
+	}
+	if exists {
+		return fail(fmt.Errorf("session %q already exists", newName))
+	}
+
+	if err := app.client.RenameSession(oldName, newName); err != nil {
+		return fail(fmt.Errorf("failed to rename session: %w", err))
+	}
+	return exitOK
+}
+
+func runSave(cmd *command, app *app, args []string) int {
+	fs := cmd.flagSet()
+	withCommands := fs.Bool("commands", false, "Also record the command running in each pane")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	snap, err := snapshot.Capture(app.client, *withCommands)
+	if err != nil {
+		return fail(fmt.Errorf("failed to capture sessions: %w", err))
+	}
+
+	path, err := snapshot.Path(app.client.Server)
+	if err != nil {
+		return fail(err)
+	}
+	if err := snapshot.Save(path, snap); err != nil {
+		return fail(fmt.Errorf("failed to save sessions: %w", err))
+	}
+
+	fmt.Printf("Saved %d sessions to %s\n", len(snap.Sessions), path)
+	return exitOK
+}
+
+func runRestore(cmd *command, app *app, args []string) int {
+	fs := cmd.flagSet()
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	path, err := snapshot.Path(app.client.Server)
+	if err != nil {
+		return fail(err)
+	}
+	snap, err := snapshot.Load(path)
+	if err != nil {
+		return fail(err)
+	}
+
+	running, err := app.client.ListSessions()
+	if err != nil {
+		return fail(err)
+	}
+
+	var sessions []snapshot.Session
+	if fs.NArg() == 0 {
+		sessions = snap.Missing(running)
+	} else {
+		for _, name := range fs.Args() {
+			session, ok := snap.Find(name)
+			if !ok {
+				return fail(fmt.Errorf("%w in %s: %s", errSessionNotFound, path, name))
+			}
+			sessions = append(sessions, *session)
+		}
+	}
+
+	code := exitOK
+	for _, session := range sessions {
+		if err := snapshot.Restore(app.client, session); err != nil {
+			code = fail(fmt.Errorf("failed to restore %s: %w", session.Name, err))
+			continue
+		}
+		fmt.Println(session.Name)
+	}
+	return code
+}
+
+func expandPath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	if path != "" {
+		if abs, err := filepath.Abs(path); err == nil {
+			return abs
+		}
+	}
+	return path
+}
+
+func getWorkingDir() string {
+	if wd, err := os.Getwd(); err == nil {
+		return wd
+	}
+	return "."
+}
+
+func runConfig(cmd *command, app *app, args []string) int {
+	fs := cmd.flagSet()
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 || fs.Arg(0) != "validate" && fs.Arg(0) != "migrate" {
+		fs.Usage()
+		return exitUsage
+	}
+
+	files := fs.Args()[1:]
+	if len(files) == 0 {
+		var err error
+		if files, err = config.Files(); err != nil {
+			return fail(err)
+		}
+		if len(files) == 0 && fs.Arg(0) == "migrate" {
+			fmt.Println("No config files found; using the defaults")
+			return exitOK
+		}
+	}
+
+	if fs.Arg(0) == "migrate" {
+		return migrateConfig(files)
+	}
+	return validateConfig(files, fs.NArg() == 1)
+}
+
+// validateConfig checks config files and project files, which are told
+// apart by name. Unless files were named, the project file of the current
+// directory is checked along with the config files.
+func validateConfig(files []string, project bool) int {
+	var configFiles, projectFiles []string
+	for _, file := range files {
+		if filepath.Base(file) == config.ProjectFile {
+			projectFiles = append(projectFiles, file)
+		} else {
+			configFiles = append(configFiles, file)
+		}
+	}
+	if path := filepath.Join(getWorkingDir(), config.ProjectFile); project {
+		if _, err := os.Stat(path); err == nil {
+			projectFiles = append(projectFiles, path)
+		}
+	}
+	if len(configFiles) == 0 && len(projectFiles) == 0 {
+		fmt.Println("No config files found; using the defaults")
+		return exitOK
+	}
+
+	// Project templates are resolved against the config they'd be used with
+	cfg := config.DefaultConfig()
+	if len(configFiles) > 0 {
+		var err error
+		if cfg, err = config.LoadFiles(configFiles...); err != nil {
+			return fail(err)
+		}
+	}
+	issues := cfg.Issues
+	for _, file := range projectFiles {
+		_, projectIssues, err := cfg.ProjectTemplate(filepath.Dir(file))
+		if err != nil {
+			return fail(err)
+		}
+		issues = append(issues, projectIssues...)
+	}
+
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if len(issues) > 0 {
+		return exitError
+	}
+
+	for _, file := range append(configFiles, projectFiles...) {
+		fmt.Printf("%s: ok\n", file)
+	}
+	return exitOK
+}
+
+// migrateConfig rewrites config files in the current schema version,
+// keeping a backup of each one it changes.
+func migrateConfig(files []string) int {
+	code := exitOK
+	for _, file := range files {
+		migrated, err := config.Migrate(file)
+		switch {
+		case err != nil:
+			code = fail(err)
+		case migrated:
+			fmt.Printf("%s: migrated to version %d (backup in %s.bak)\n", file, config.Version, file)
+		default:
+			fmt.Printf("%s: up to date\n", file)
+		}
+	}
+	return code
+}
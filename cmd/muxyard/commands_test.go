@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"muxyard/internal/config"
+	"muxyard/internal/tmux"
+)
+
+func newTestApp(t *testing.T, sessions string) (*app, *tmux.FakeRunner) {
+	t.Helper()
+	t.Setenv("TMUX", "")
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	runner := tmux.NewFakeRunner()
+	runner.Respond("list-sessions", sessions, nil)
+	cfg := &config.Config{Templates: []config.SessionTemplate{
+		{Name: "basic", Windows: []config.WindowConfig{{Name: "main"}}},
+		{Name: "coding", Windows: []config.WindowConfig{{Name: "editor", Command: "nvim ."}}},
+		{
+			Name:    "service",
+			Params:  []config.TemplateParam{{Name: "port"}},
+			Windows: []config.WindowConfig{{Name: "server", Command: "serve --port {{.port}}"}},
+		},
+	}}
+	client := tmux.NewClient(runner)
+	return &app{cfg: cfg, client: client, servers: []*tmux.Client{client}}, runner
+}
+
+func run(t *testing.T, app *app, args ...string) int {
+	t.Helper()
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		t.Fatalf("unknown command %q", args[0])
+	}
+	return cmd.run(cmd, app, args[1:])
+}
+
+func assertCommands(t *testing.T, runner *tmux.FakeRunner, expected ...string) {
+	t.Helper()
+	if len(expected) == 0 && len(runner.Calls) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(runner.Commands(), expected) {
+		t.Errorf("tmux ran\n%s\nwant\n%s", strings.Join(runner.Commands(), "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestNewSession(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		args     []string
+		code     int
+		expected []string
+	}{
+		{
+			name: "first template by default",
+			args: []string{"--path", dir, "--name", "scratch", "--detach"},
+			expected: []string{
+				"list-sessions -F #{session_name}:#{session_windows}:#{session_attached}:#{session_activity}:#{session_path}",
+				"new-session -d -s scratch -P -F #{window_id} #{pane_id} -c " + dir + " -n main",
+			},
+		},
+		{
+			name: "named template and attach",
+			args: []string{"--path", dir, "--name", "scratch", "--template", "coding"},
+			expected: []string{
+				"list-sessions -F #{session_name}:#{session_windows}:#{session_attached}:#{session_activity}:#{session_path}",
+				"new-session -d -s scratch -P -F #{window_id} #{pane_id} -c " + dir + " -n editor sh -c nvim .; exec $SHELL",
+				"attach-session -t scratch",
+			},
+		},
+		{
+			name: "parameter",
+			args: []string{"--path", dir, "--name", "svc", "--template", "service", "--param", "port=9000", "--detach"},
+			expected: []string{
+				"list-sessions -F #{session_name}:#{session_windows}:#{session_attached}:#{session_activity}:#{session_path}",
+				"new-session -d -s svc -P -F #{window_id} #{pane_id} -c " + dir + " -n server sh -c serve --port 9000; exec $SHELL",
+			},
+		},
+		{name: "existing name", args: []string{"--path", dir, "--name", "api"}, code: exitError},
+		{name: "unknown template", args: []string{"--path", dir, "--template", "nope"}, code: exitError},
+		{name: "missing parameter", args: []string{"--path", dir, "--template", "service"}, code: exitError},
+		{name: "empty parameter", args: []string{"--path", dir, "--template", "service", "--param", "port="}, code: exitError},
+		{name: "undeclared parameter", args: []string{"--path", dir, "--template", "service", "--param", "port=9000", "--param", "prot=1"}, code: exitError},
+		{name: "missing directory", args: []string{"--path", dir + "/nope"}, code: exitError},
+		{name: "malformed parameter", args: []string{"--param", "port"}, code: exitUsage},
+		{name: "stray argument", args: []string{"api"}, code: exitUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, runner := newTestApp(t, "api:2:0:1700000000:/src/api\n")
+			if code := run(t, app, append([]string{"new"}, tt.args...)...); code != tt.code {
+				t.Fatalf("Expected exit code %d, got %d", tt.code, code)
+			}
+			if tt.code == exitOK {
+				assertCommands(t, runner, tt.expected...)
+			}
+		})
+	}
+}
+
+func TestNewSessionNamesAfterDirectory(t *testing.T) {
+	dir := t.TempDir() + "/my.api"
+	app, runner := newTestApp(t, "my_api:1:0:1700000000:/src/my_api\n")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := run(t, app, "new", "--path", dir, "--detach"); code != exitOK {
+		t.Fatalf("Expected success, got exit code %d", code)
+	}
+	if got := runner.Commands()[1]; !strings.Contains(got, "-s my_api_2 ") {
+		t.Errorf("Expected a unique session name derived from the directory, got %q", got)
+	}
+}
+
+func TestKillSessions(t *testing.T) {
+	app, runner := newTestApp(t, "api:2:0:1700000000:/src/api\nweb:1:0:1700000000:/src/web\n")
+	if code := run(t, app, "kill", "api", "docs", "web"); code != exitNotFound {
+		t.Errorf("Expected exit code %d for the missing session, got %d", exitNotFound, code)
+	}
+	var killed []string
+	for _, command := range runner.Commands() {
+		if strings.HasPrefix(command, "kill-session") {
+			killed = append(killed, command)
+		}
+	}
+	if want := []string{"kill-session -t api", "kill-session -t web"}; !reflect.DeepEqual(killed, want) {
+		t.Errorf("Expected the other sessions to be killed anyway, got %q", killed)
+	}
+
+	app, runner = newTestApp(t, "api:2:0:1700000000:/src/api\n")
+	runner.Respond("kill-session", "", errors.New("server exited"))
+	if code := run(t, app, "kill", "api"); code != exitError {
+		t.Errorf("Expected exit code %d for a failed kill, got %d", exitError, code)
+	}
+
+	if code := run(t, app, "kill"); code != exitUsage {
+		t.Errorf("Expected exit code %d without names, got %d", exitUsage, code)
+	}
+}
+
+func TestRenameSessionCommand(t *testing.T) {
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{[]string{"api", "backend"}, exitOK},
+		{[]string{"docs", "backend"}, exitNotFound},
+		{[]string{"api", "web"}, exitError},
+		{[]string{"api"}, exitUsage},
+	}
+
+	for _, tt := range tests {
+		app, runner := newTestApp(t, "api:2:0:1700000000:/src/api\nweb:1:0:1700000000:/src/web\n")
+		if code := run(t, app, append([]string{"rename"}, tt.args...)...); code != tt.code {
+			t.Errorf("rename %q: expected exit code %d, got %d", tt.args, tt.code, code)
+		}
+		renamed := strings.Contains(strings.Join(runner.Commands(), "\n"), "rename-session -t api backend")
+		if renamed != (tt.code == exitOK) {
+			t.Errorf("rename %q: expected rename-session only on success, got\n%s", tt.args, strings.Join(runner.Commands(), "\n"))
+		}
+	}
+}
+
+func TestSelectTemplate(t *testing.T) {
+	app, _ := newTestApp(t, "")
+	dir := t.TempDir()
+
+	if template, err := selectTemplate(app.cfg, "", dir, true); err != nil || template.Name != "basic" {
+		t.Errorf("Expected the first template, got %+v (err %v)", template, err)
+	}
+	if template, err := selectTemplate(app.cfg, "coding", dir, true); err != nil || template.Name != "coding" {
+		t.Errorf("Expected the named template, got %+v (err %v)", template, err)
+	}
+	if _, err := selectTemplate(&config.Config{}, "", dir, true); err == nil {
+		t.Error("Expected an error without templates")
+	}
+}
+
+func TestListEveryServer(t *testing.T) {
+	app, _ := newTestApp(t, "api:2:0:1700000000:/src/api\n")
+	down := tmux.NewFakeRunner()
+	down.Respond("list-sessions", "", errors.New("no server running"))
+	app.servers = append(app.servers, tmux.NewServerClient("scratch", down))
+
+	if code := run(t, app, "ls"); code != exitError {
+		t.Errorf("Expected exit code %d with a server down, got %d", exitError, code)
+	}
+	if len(down.Calls) != 1 {
+		t.Errorf("Expected the additional server to be asked too, got %q", down.Commands())
+	}
+}
+
+func TestNewSessionFromProjectTemplate(t *testing.T) {
+	dir := t.TempDir()
+	project := "name: api\nwindows:\n  - name: server\n    command: make run\n  - name: split\n    panes: [{command: top}, {split: vertical}]\n"
+	if err := os.WriteFile(filepath.Join(dir, config.ProjectFile), []byte(project), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	app, runner := newTestApp(t, "")
+	if template, err := selectTemplate(app.cfg, "", dir, true); err != nil || template.Source == "" {
+		t.Fatalf("Expected the project template, got %+v (err %v)", template, err)
+	} else {
+		var report bytes.Buffer
+		reportProjectTemplate(&report, template)
+		want := "muxyard: using project template from " + filepath.Join(dir, config.ProjectFile) + " (--no-project-config to ignore it)\n" +
+			"  server: make run\n  split: top\n"
+		if report.String() != want {
+			t.Errorf("Expected the report\n%s\ngot\n%s", want, report.String())
+		}
+	}
+
+	if code := run(t, app, "new", "--path", dir, "--name", "api", "--no-project-config", "--detach"); code != exitOK {
+		t.Fatalf("Expected success, got exit code %d", code)
+	}
+	if got := runner.Commands()[1]; !strings.HasSuffix(got, " -n main") {
+		t.Errorf("Expected the first configured template with --no-project-config, got %q", got)
+	}
+}
+
+func TestValidateProjectFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, config.ProjectFile)
+	if err := os.WriteFile(path, []byte("windows:\n  - name: server\n    comand: make run\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	app, _ := newTestApp(t, "")
+	if code := run(t, app, "config", "validate", path); code != exitError {
+		t.Errorf("Expected exit code %d for the unknown field, got %d", exitError, code)
+	}
+
+	if err := os.WriteFile(path, []byte("windows: [broken\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := selectTemplate(app.cfg, "", dir, true); err == nil {
+		t.Error("Expected an unusable project template to stop the session")
+	}
+	if template, err := selectTemplate(app.cfg, "", dir, false); err != nil || template.Name != "basic" {
+		t.Errorf("Expected --no-project-config to get past it, got %+v (err %v)", template, err)
+	}
+}
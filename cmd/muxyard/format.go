@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"muxyard/internal/git"
+	"muxyard/internal/tmux"
+)
+
+const formatUsage = "Output format: text, json, tsv, or a Go template such as '{{.Name}}'"
+
+type sessionRecord struct {
+	Name     string `json:"name"`
+	Windows  int    `json:"windows"`
+	Attached bool   `json:"attached"`
+	// Server is the label of the tmux server the session runs on.
+	Server string `json:"server"`
+	// showServer adds the server to the text output, which only needs it
+	// when sessions of several servers are listed.
+	showServer bool
+}
+
+func (r sessionRecord) text() []string {
+	status := "detached"
+	if r.Attached {
+		status = "attached"
+	}
+	text := []string{r.Name, fmt.Sprintf("%d windows", r.Windows), status}
+	if r.showServer {
+		text = append(text, r.Server)
+	}
+	return text
+}
+
+func (r sessionRecord) fields() []string {
+	return []string{r.Name, strconv.Itoa(r.Windows), strconv.FormatBool(r.Attached), r.Server}
+}
+
+type repoRecord struct {
+	Name   string  `json:"name"`
+	Path   string  `json:"path"`
+	Kind   string  `json:"kind"`
+	Branch string  `json:"branch,omitempty"`
+	Main   string  `json:"main,omitempty"`
+	Marker string  `json:"marker,omitempty"`
+	Score  float64 `json:"score,omitempty"`
+}
+
+func (r repoRecord) text() []string {
+	return []string{r.Name, r.Path}
+}
+
+func (r repoRecord) fields() []string {
+	return []string{r.Name, r.Path}
+}
+
+type record interface {
+	text() []string
+	fields() []string
+}
+
+func sessionRecords(sessions []tmux.Session, showServer bool) []sessionRecord {
+	records := make([]sessionRecord, len(sessions))
+	for i, session := range sessions {
+		records[i] = sessionRecord{
+			Name:       session.Name,
+			Windows:    session.Windows,
+			Attached:   session.Attached,
+			Server:     session.Server,
+			showServer: showServer,
+		}
+	}
+	return records
+}
+
+func repoRecords(repos []git.Repository) []repoRecord {
+	records := make([]repoRecord, len(repos))
+	for i, repo := range repos {
+		records[i] = repoRecord{
+			Name:   repo.Name,
+			Path:   repo.Path,
+			Kind:   repo.Kind.String(),
+			Branch: repo.Branch,
+			Main:   repo.MainPath,
+			Marker: repo.Marker,
+			Score:  repo.Score,
+		}
+	}
+	return records
+}
+
+// writeRecords renders records in the requested format. Anything that isn't
+// a named format is treated as a text/template executed once per record.
+func writeRecords[T record](w io.Writer, format string, records []T) error {
+	switch format {
+	case "", "text":
+		for _, r := range records {
+			fmt.Fprintln(w, strings.Join(r.text(), "\t"))
+		}
+		return nil
+
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if records == nil {
+			records = []T{}
+		}
+		return enc.Encode(records)
+
+	case "tsv":
+		for _, r := range records {
+			fmt.Fprintln(w, strings.Join(r.fields(), "\t"))
+		}
+		return nil
+	}
+
+	if !strings.Contains(format, "{{") {
+		return fmt.Errorf("unknown format %q (want text, json, tsv, or a Go template)", format)
+	}
+
+	tmpl, err := template.New("format").Parse(format)
+	if err != nil {
+		return fmt.Errorf("invalid format template: %w", err)
+	}
+	for _, r := range records {
+		if err := tmpl.Execute(w, r); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
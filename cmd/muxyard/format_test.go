@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteRecords(t *testing.T) {
+	records := []sessionRecord{
+		{Name: "api", Windows: 3, Attached: true, Server: "default"},
+		{Name: "web", Windows: 1, Attached: false, Server: "work"},
+	}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{"text", "api\t3 windows\tattached\nweb\t1 windows\tdetached\n"},
+		{"tsv", "api\t3\ttrue\tdefault\nweb\t1\tfalse\twork\n"},
+		{"{{.Name}}:{{.Windows}}@{{.Server}}", "api:3@default\nweb:1@work\n"},
+		{"json", "[\n  {\n    \"name\": \"api\",\n    \"windows\": 3,\n    \"attached\": true,\n    \"server\": \"default\"\n  },\n" +
+			"  {\n    \"name\": \"web\",\n    \"windows\": 1,\n    \"attached\": false,\n    \"server\": \"work\"\n  }\n]\n"},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := writeRecords(&buf, tt.format, records); err != nil {
+			t.Fatalf("writeRecords(%q) failed: %v", tt.format, err)
+		}
+		if buf.String() != tt.expected {
+			t.Errorf("writeRecords(%q) = %q, want %q", tt.format, buf.String(), tt.expected)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := writeRecords(&buf, "yaml", records); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+
+	buf.Reset()
+	if err := writeRecords(&buf, "json", []repoRecord(nil)); err != nil || buf.String() != "[]\n" {
+		t.Errorf("Expected empty JSON array, got %q (err %v)", buf.String(), err)
+	}
+}
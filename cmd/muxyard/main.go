@@ -16,6 +16,9 @@ var version = "dev" // Set by build flags
 func main() {
 	var showVersion = flag.Bool("version", false, "Show version information")
 	var showHelp = flag.Bool("help", false, "Show help information")
+	var socketName = flag.String("socket-name", "", "tmux socket name, like tmux -L")
+	var socketPath = flag.String("socket", "", "tmux socket path, like tmux -S")
+	flag.Usage = func() { printUsage(os.Stderr) }
 	flag.Parse()
 
 	if *showVersion {
@@ -24,17 +27,30 @@ func main() {
 	}
 
 	if *showHelp {
-		fmt.Println("Muxyard - Tmux Session Manager")
-		fmt.Println("")
-		fmt.Println("Usage:")
-		fmt.Println("  muxyard              Start the interactive TUI")
-		fmt.Println("  muxyard --version    Show version information")
-		fmt.Println("  muxyard --help       Show this help message")
-		fmt.Println("")
-		fmt.Println("For more information, visit: https://github.com/rubenhesselink/muxyard")
+		printUsage(os.Stdout)
 		os.Exit(0)
 	}
 
+	cfg, err := config.Load()
+	if err != nil {
+		// The config subcommands read the files themselves, and are how a
+		// config that fails to load gets diagnosed and fixed
+		if flag.Arg(0) != "config" {
+			fmt.Printf("Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+		cfg = config.DefaultConfig()
+	}
+
+	if *socketName != "" || *socketPath != "" {
+		cfg.Tmux.SocketName = *socketName
+		cfg.Tmux.SocketPath = *socketPath
+	}
+
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(cfg, flag.Arg(0), flag.Args()[1:]))
+	}
+
 	// Check if tmux is available
 	if !tmux.IsTmuxAvailable() {
 		fmt.Println("Error: tmux is not installed or not found in PATH")
@@ -42,13 +58,7 @@ func main() {
 		os.Exit(1)
 	}
 
-	cfg, err := config.Load()
-	if err != nil {
-		fmt.Printf("Error loading config: %v\n", err)
-		os.Exit(1)
-	}
-
-	p := tea.NewProgram(ui.NewMainModel(cfg), tea.WithAltScreen())
+	p := tea.NewProgram(ui.NewMainModel(cfg, tmux.NewClients(cfg.Tmux)), tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error running program: %v\n", err)
 		os.Exit(1)
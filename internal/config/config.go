@@ -4,72 +4,311 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
 type SessionTemplate struct {
-	Name          string         `yaml:"name"`
-	Description   string         `yaml:"description"`
-	Windows       []WindowConfig `yaml:"windows"`
-	FocusedWindow string         `yaml:"focused_window,omitempty"`
+	Name          string          `yaml:"name"`
+	Description   string          `yaml:"description,omitempty"`
+	Windows       []WindowConfig  `yaml:"windows"`
+	FocusedWindow string          `yaml:"focused_window,omitempty"`
+	Params        []TemplateParam `yaml:"params,omitempty"`
+	// Extends names a template whose windows, description, focused window,
+	// and params this one inherits. Include appends windows from fragments,
+	// Remove drops inherited windows by name, and windows named like an
+	// inherited one replace it.
+	Extends string   `yaml:"extends,omitempty"`
+	Include []string `yaml:"include,omitempty"`
+	Remove  []string `yaml:"remove,omitempty"`
+	// Source is the file a project template was read from; empty for
+	// templates from the global config.
+	Source string `yaml:"-"`
+}
+
+// TemplateParam is a value the user is asked for before a session is
+// created from the template, available to it as {{.name}}.
+type TemplateParam struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	Default     string `yaml:"default,omitempty"`
 }
 
 type WindowConfig struct {
-	Name    string `yaml:"name,omitempty"`
+	Name    string       `yaml:"name,omitempty"`
+	Command string       `yaml:"command,omitempty"`
+	Path    string       `yaml:"path,omitempty"`
+	Layout  string       `yaml:"layout,omitempty"`
+	Panes   []PaneConfig `yaml:"panes,omitempty"`
+}
+
+// PaneConfig describes one pane of a window. The first pane of a window is
+// the one tmux creates with the window itself, so its Split and Size are
+// ignored; every following pane is split off the previous one.
+type PaneConfig struct {
 	Command string `yaml:"command,omitempty"`
+	Split   string `yaml:"split,omitempty"`
+	Size    string `yaml:"size,omitempty"`
+	Path    string `yaml:"path,omitempty"`
+	Focus   bool   `yaml:"focus,omitempty"`
+}
+
+// SplitHorizontal reports whether the pane is split off beside the previous
+// one rather than below it. Split is horizontal or vertical, or h or v for
+// short, and defaults to vertical.
+func (p PaneConfig) SplitHorizontal() (bool, error) {
+	switch p.Split {
+	case "", "vertical", "v":
+		return false, nil
+	case "horizontal", "h":
+		return true, nil
+	}
+	return false, fmt.Errorf("unknown split direction %q (want horizontal, vertical, h, or v)", p.Split)
 }
 
+// ColorConfig overrides individual colors of the theme. Empty fields keep
+// the theme's color.
 type ColorConfig struct {
-	Title        ColorPair `yaml:"title"`
-	Selected     string    `yaml:"selected"`
-	Dimmed       string    `yaml:"dimmed"`
-	Help         string    `yaml:"help"`
-	Error        string    `yaml:"error"`
-	Success      string    `yaml:"success"`
-	Border       string    `yaml:"border"`
-	Input        string    `yaml:"input"`
-	FocusedInput string    `yaml:"focused_input"`
-	Spinner      string    `yaml:"spinner"`
-	Highlight    string    `yaml:"highlight"`
-	FilterBorder string    `yaml:"filter_border"`
+	Title        ColorPair `yaml:"title,omitempty"`
+	Selected     string    `yaml:"selected,omitempty"`
+	Dimmed       string    `yaml:"dimmed,omitempty"`
+	Help         string    `yaml:"help,omitempty"`
+	Error        string    `yaml:"error,omitempty"`
+	Success      string    `yaml:"success,omitempty"`
+	Border       string    `yaml:"border,omitempty"`
+	Input        string    `yaml:"input,omitempty"`
+	FocusedInput string    `yaml:"focused_input,omitempty"`
+	Spinner      string    `yaml:"spinner,omitempty"`
+	Highlight    string    `yaml:"highlight,omitempty"`
+	FilterBorder string    `yaml:"filter_border,omitempty"`
 }
 
 type ColorPair struct {
-	Foreground string `yaml:"foreground"`
-	Background string `yaml:"background"`
+	Foreground string `yaml:"foreground,omitempty"`
+	Background string `yaml:"background,omitempty"`
+}
+
+// Themes are the names of the built-in color themes. Their colors are
+// defined by the ui package.
+var Themes = []string{"default", "dark", "light", "monochrome", "solarized", "catppuccin"}
+
+// TmuxConfig selects the tmux server muxyard manages. SocketName and
+// SocketPath correspond to tmux's -L and -S flags; Servers lists further
+// servers whose sessions are shown alongside it in the TUI.
+type TmuxConfig struct {
+	SocketName string         `yaml:"socket_name,omitempty"`
+	SocketPath string         `yaml:"socket_path,omitempty"`
+	Servers    []ServerConfig `yaml:"servers,omitempty"`
+}
+
+// RepoDirectory is a directory searched for repositories, with the rules of
+// the search. It can be written as just its path.
+type RepoDirectory struct {
+	Path string `yaml:"path"`
+	// MaxDepth is how many levels below Path repositories are looked for.
+	MaxDepth int `yaml:"max_depth,omitempty"`
+	// Exclude are globs of directories not to look in. A glob without a
+	// slash matches directory names, one with a slash paths relative to
+	// Path.
+	Exclude []string `yaml:"exclude,omitempty"`
+	// Include are globs, matched like Exclude, that repositories have to
+	// match to be listed. All repositories are listed when there are none.
+	Include        []string `yaml:"include,omitempty"`
+	FollowSymlinks bool     `yaml:"follow_symlinks,omitempty"`
+}
+
+// DefaultProjectMarkers find version controlled directories and the usual
+// kinds of projects.
+var DefaultProjectMarkers = []string{".git", ".hg", ".jj", "go.mod", "package.json", ProjectFile}
+
+// Markers returns ProjectMarkers, or the default when they aren't set.
+func (c *Config) Markers() []string {
+	if c.ProjectMarkers == nil {
+		return DefaultProjectMarkers
+	}
+	return c.ProjectMarkers
+}
+
+// DefaultMaxDepth and DefaultExclude apply to repository directories that
+// don't set their own.
+const DefaultMaxDepth = 3
+
+var DefaultExclude = []string{".*", "node_modules", "vendor", "target"}
+
+func (d *RepoDirectory) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*d = RepoDirectory{}
+		return node.Decode(&d.Path)
+	}
+	type plain RepoDirectory
+	return node.Decode((*plain)(d))
+}
+
+// MarshalYAML writes directories without rules as just their path, the way
+// they are usually written by hand.
+func (d RepoDirectory) MarshalYAML() (any, error) {
+	if d.MaxDepth == 0 && d.Exclude == nil && d.Include == nil && !d.FollowSymlinks {
+		return d.Path, nil
+	}
+	type plain RepoDirectory
+	return plain(d), nil
+}
+
+// Depth returns MaxDepth, or the default when it isn't set.
+func (d RepoDirectory) Depth() int {
+	if d.MaxDepth <= 0 {
+		return DefaultMaxDepth
+	}
+	return d.MaxDepth
+}
+
+// Excluded returns Exclude, or the default when it isn't set. An empty list
+// excludes nothing.
+func (d RepoDirectory) Excluded() []string {
+	if d.Exclude == nil {
+		return DefaultExclude
+	}
+	return d.Exclude
+}
+
+// WorktreeConfig controls the worktrees muxyard adds.
+type WorktreeConfig struct {
+	// Path is where a new worktree goes: a template of the repository name
+	// and branch, relative to the repository unless absolute.
+	Path string `yaml:"path,omitempty"`
+}
+
+// DefaultWorktreePath puts worktrees next to their repository.
+const DefaultWorktreePath = "../{{.repo}}-{{.branch}}"
+
+// ZoxideConfig adds the directories in zoxide's database to the repository
+// list, ranked by their zoxide scores.
+type ZoxideConfig struct {
+	// Enabled is a pointer so that a later config file can turn off what
+	// an earlier one turned on.
+	Enabled *bool `yaml:"enabled,omitempty"`
+	// MinScore leaves out the directories zoxide scores lower, such as
+	// ones visited once long ago.
+	MinScore float64 `yaml:"min_score,omitempty"`
+}
+
+// On reports whether the zoxide directories are listed.
+func (z ZoxideConfig) On() bool {
+	return z.Enabled != nil && *z.Enabled
+}
+
+// KeysConfig maps UI actions to the keys that trigger them, in the notation
+// of bubbletea key messages ("enter", "ctrl+v", "space", ...). Actions left
+// out keep their default keys; an empty list disables an action.
+type KeysConfig struct {
+	Up             KeyList `yaml:"up,omitempty"`
+	Down           KeyList `yaml:"down,omitempty"`
+	Select         KeyList `yaml:"select,omitempty"`
+	Back           KeyList `yaml:"back,omitempty"`
+	MenuBack       KeyList `yaml:"menu_back,omitempty"`
+	Quit           KeyList `yaml:"quit,omitempty"`
+	Attach         KeyList `yaml:"attach,omitempty"`
+	Create         KeyList `yaml:"create,omitempty"`
+	Rename         KeyList `yaml:"rename,omitempty"`
+	Delete         KeyList `yaml:"delete,omitempty"`
+	Filter         KeyList `yaml:"filter,omitempty"`
+	Visual         KeyList `yaml:"visual,omitempty"`
+	Tree           KeyList `yaml:"tree,omitempty"`
+	Preview        KeyList `yaml:"preview,omitempty"`
+	Expand         KeyList `yaml:"expand,omitempty"`
+	Collapse       KeyList `yaml:"collapse,omitempty"`
+	Submit         KeyList `yaml:"submit,omitempty"`
+	Cancel         KeyList `yaml:"cancel,omitempty"`
+	Confirm        KeyList `yaml:"confirm,omitempty"`
+	Deny           KeyList `yaml:"deny,omitempty"`
+	Sort           KeyList `yaml:"sort,omitempty"`
+	DirtyOnly      KeyList `yaml:"dirty_only,omitempty"`
+	AddWorktree    KeyList `yaml:"add_worktree,omitempty"`
+	RemoveWorktree KeyList `yaml:"remove_worktree,omitempty"`
+	Rescan         KeyList `yaml:"rescan,omitempty"`
+}
+
+// KeyList is one or more keys, written as a single key or a list.
+type KeyList []string
+
+func (k *KeyList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*k = KeyList{node.Value}
+		return nil
+	}
+	var keys []string
+	if err := node.Decode(&keys); err != nil {
+		return err
+	}
+	*k = append(KeyList{}, keys...)
+	return nil
+}
+
+// IsZero tells yaml that an empty list, which disables an action, is still
+// to be written.
+func (k KeyList) IsZero() bool {
+	return k == nil
+}
+
+// MarshalYAML writes a single key the way it is usually written by hand.
+func (k KeyList) MarshalYAML() (any, error) {
+	if len(k) == 1 {
+		return k[0], nil
+	}
+	return []string(k), nil
+}
+
+type ServerConfig struct {
+	Name       string `yaml:"name"`
+	SocketName string `yaml:"socket_name,omitempty"`
+	SocketPath string `yaml:"socket_path,omitempty"`
 }
 
 type Config struct {
-	RepoDirectories []string          `yaml:"repo_directories"`
-	Templates       []SessionTemplate `yaml:"templates"`
-	Colors          ColorConfig       `yaml:"colors,omitempty"`
+	Version         int             `yaml:"version,omitempty"`
+	RepoDirectories []RepoDirectory `yaml:"repo_directories,omitempty"`
+	// ProjectMarkers are the files and directories that make a directory
+	// a project, in order of precedence.
+	ProjectMarkers []string          `yaml:"project_markers,omitempty"`
+	Templates      []SessionTemplate `yaml:"templates,omitempty"`
+	// Fragments are named lists of windows that templates can include.
+	Fragments map[string][]WindowConfig `yaml:"fragments,omitempty"`
+	// Theme is one of Themes, with Colors overriding single colors of it.
+	Theme     string         `yaml:"theme,omitempty"`
+	Colors    ColorConfig    `yaml:"colors,omitempty"`
+	Tmux      TmuxConfig     `yaml:"tmux,omitempty"`
+	Worktrees WorktreeConfig `yaml:"worktrees,omitempty"`
+	Zoxide    ZoxideConfig   `yaml:"zoxide,omitempty"`
+	Keys      KeysConfig     `yaml:"keys,omitempty"`
+	// Issues are the problems found while loading the config file.
+	Issues []Issue `yaml:"-"`
+
+	// user is the user config file's own layer of a loaded config; see User.
+	user *Config
+}
+
+// User returns the settings of the user config file as written there: with
+// paths unexpanded, templates unresolved, and without the settings of the
+// other files. It is what Save writes for a loaded config, so changes meant
+// for the user file are made to it. A config that wasn't loaded from files
+// is its own user layer.
+func (c *Config) User() *Config {
+	if c.user == nil {
+		return c
+	}
+	return c.user
 }
 
 func DefaultConfig() *Config {
 	return &Config{
-		RepoDirectories: []string{
-			filepath.Join(os.Getenv("HOME"), "src"),
-			filepath.Join(os.Getenv("HOME"), "code"),
-			filepath.Join(os.Getenv("HOME"), "projects"),
-		},
-		Colors: ColorConfig{
-			Title: ColorPair{
-				Foreground: "#FAFAFA",
-				Background: "#7D56F4",
-			},
-			Selected:     "#EE6FF8",
-			Dimmed:       "#626262",
-			Help:         "#626262",
-			Error:        "#FF0000",
-			Success:      "#00FF00",
-			Border:       "#874BFD",
-			Input:        "#874BFD",
-			FocusedInput: "#FF75B7",
-			Spinner:      "205",
-			Highlight:    "#FF75B7",
-			FilterBorder: "#FF75B7",
+		Version: Version,
+		RepoDirectories: []RepoDirectory{
+			{Path: filepath.Join(os.Getenv("HOME"), "src")},
+			{Path: filepath.Join(os.Getenv("HOME"), "code")},
+			{Path: filepath.Join(os.Getenv("HOME"), "projects")},
 		},
+		Theme: "default",
 		Templates: []SessionTemplate{
 			{
 				Name:        "basic",
@@ -110,6 +349,64 @@ func (c *Config) GetTemplate(name string) (*SessionTemplate, error) {
 	return nil, fmt.Errorf("template %q not found", name)
 }
 
+// ProjectFile is the name of the per-repository template file.
+const ProjectFile = ".muxyard.yaml"
+
+// LoadProjectTemplate reads the template committed in a project directory.
+// Problems with it are reported as issues, like those of the config files;
+// a file that isn't valid YAML returns a nil template. It returns nil
+// without an error when the directory has no project file.
+func LoadProjectTemplate(dir string) (*SessionTemplate, []Issue, error) {
+	template, _, issues, err := loadProjectTemplate(dir)
+	return template, issues, err
+}
+
+func loadProjectTemplate(dir string) (*SessionTemplate, *yaml.Node, []Issue, error) {
+	path := filepath.Join(dir, ProjectFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, nil, []Issue{yamlIssue(path, strings.TrimPrefix(err.Error(), "yaml: "))}, nil
+	}
+	var template SessionTemplate
+	issues := decodeNode(path, &root, &template)
+
+	if template.Name == "" {
+		template.Name = filepath.Base(dir)
+	}
+	template.Source = path
+	return &template, &root, issues, nil
+}
+
+// ProjectTemplate loads the project template in dir, like
+// LoadProjectTemplate, and resolves it against the configured templates.
+// The resolved template is checked like the configured ones; one that
+// can't be resolved or has no windows is reported and returned as nil.
+func (c *Config) ProjectTemplate(dir string) (*SessionTemplate, []Issue, error) {
+	template, root, issues, err := loadProjectTemplate(dir)
+	if err != nil || template == nil {
+		return nil, issues, err
+	}
+
+	origin := templateOrigin{file: template.Source, root: root}
+	resolved, err := c.ResolveTemplate(template)
+	if err != nil {
+		return nil, append(issues, origin.issue("%v", err)), nil
+	}
+	issues = append(issues, checkTemplate(*resolved, origin)...)
+	if len(resolved.Windows) == 0 {
+		return nil, issues, nil
+	}
+	return resolved, issues, nil
+}
+
 func configDir() (string, error) {
 	configHome := os.Getenv("XDG_CONFIG_HOME")
 	if configHome == "" {
@@ -130,13 +427,15 @@ func configPath() (string, error) {
 	return filepath.Join(dir, "config.yaml"), nil
 }
 
+// Load merges every config file that exists; see Files for the order. When
+// there is none, the default config is written to the user config file.
 func Load() (*Config, error) {
-	path, err := configPath()
+	files, err := Files()
 	if err != nil {
 		return nil, err
 	}
 
-	if _, err := os.Stat(path); os.IsNotExist(err) {
+	if len(files) == 0 {
 		cfg := DefaultConfig()
 		if err := Save(cfg); err != nil {
 			return nil, fmt.Errorf("failed to create default config: %w", err)
@@ -144,19 +443,12 @@ func Load() (*Config, error) {
 		return cfg, nil
 	}
 
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, err
-	}
-
-	return &cfg, nil
+	return LoadFiles(files...)
 }
 
+// Save writes the config's User layer to the user config file. An existing
+// file is updated through its node tree rather than rewritten, so comments
+// and formatting survive wherever the settings still match.
 func Save(cfg *Config) error {
 	dir, err := configDir()
 	if err != nil {
@@ -172,7 +464,29 @@ func Save(cfg *Config) error {
 		return err
 	}
 
-	data, err := yaml.Marshal(cfg)
+	saved := *cfg.User()
+	saved.Version = Version
+	var updated yaml.Node
+	if err := updated.Encode(&saved); err != nil {
+		return err
+	}
+
+	root := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&updated}}
+	if data, err := os.ReadFile(path); err == nil {
+		var existing yaml.Node
+		if err := yaml.Unmarshal(data, &existing); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if len(existing.Content) > 0 {
+			if _, err := upgrade(&existing); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			mergeNodes(existing.Content[0], &updated)
+			root = &existing
+		}
+	}
+
+	data, err := encodeNode(root)
 	if err != nil {
 		return err
 	}
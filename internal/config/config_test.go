@@ -0,0 +1,953 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadProjectTemplate(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "api")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	template, issues, err := LoadProjectTemplate(dir)
+	if err != nil || template != nil || issues != nil {
+		t.Fatalf("Expected no template without %s, got %+v (err %v)", ProjectFile, template, err)
+	}
+
+	writeFile(t, filepath.Join(dir, ProjectFile), `
+description: API workspace
+windows:
+  - name: editor
+    command: nvim .
+  - name: server
+    command: make run
+`)
+
+	template, issues, err = LoadProjectTemplate(dir)
+	if err != nil || len(issues) != 0 {
+		t.Fatalf("LoadProjectTemplate failed: %v (err %v)", issues, err)
+	}
+	if template.Name != "api" {
+		t.Errorf("Expected name to default to the directory name, got %q", template.Name)
+	}
+	if template.Source != filepath.Join(dir, ProjectFile) {
+		t.Errorf("Expected source %q, got %q", filepath.Join(dir, ProjectFile), template.Source)
+	}
+	if len(template.Windows) != 2 || template.Windows[1].Command != "make run" {
+		t.Errorf("Unexpected windows: %+v", template.Windows)
+	}
+
+	writeFile(t, filepath.Join(dir, ProjectFile), "name: [broken\n")
+	if template, issues, err := LoadProjectTemplate(dir); err != nil || template != nil || len(issues) != 1 {
+		t.Errorf("Expected invalid YAML to be reported as an issue, got %+v %v (err %v)", template, issues, err)
+	}
+}
+
+func TestProjectTemplateIssues(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ProjectFile), `name: api
+focused_windw: server
+focused_window: logs
+windows:
+  - name: server
+    comand: make run
+  - name: split
+    panes:
+      - command: top
+      - split: diagonal
+`)
+
+	template, issues, err := DefaultConfig().ProjectTemplate(dir)
+	if err != nil || template == nil {
+		t.Fatalf("Expected the template despite its issues, got %+v (err %v)", template, err)
+	}
+	var got []string
+	for _, issue := range issues {
+		issue.File = ProjectFile
+		got = append(got, issue.String())
+	}
+	want := []string{
+		`.muxyard.yaml:2: unknown field "focused_windw" in template (did you mean "focused_window"?)`,
+		`.muxyard.yaml:6: unknown field "comand" in window (did you mean "command"?)`,
+		`.muxyard.yaml:10: template "api": window "split" pane 2: split must be horizontal, vertical, h, or v, got "diagonal"`,
+		`.muxyard.yaml:3: focused_window "logs" names no window of template "api"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected issues\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	writeFile(t, filepath.Join(dir, ProjectFile), "name: empty\nwindows: []\n")
+	template, issues, err = DefaultConfig().ProjectTemplate(dir)
+	if err != nil || template != nil || len(issues) != 1 || issues[0].Line != 2 {
+		t.Errorf("Expected a project template without windows to be reported and left out, got %+v %v (err %v)", template, issues, err)
+	}
+
+	writeFile(t, filepath.Join(dir, ProjectFile), "extends: nope\n")
+	if template, issues, err = DefaultConfig().ProjectTemplate(dir); err != nil || template != nil || len(issues) != 1 {
+		t.Errorf("Expected a broken extends to be reported and left out, got %+v %v (err %v)", template, issues, err)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	template := &SessionTemplate{
+		Name:          "service",
+		FocusedWindow: "{{.repo}}",
+		Params:        []TemplateParam{{Name: "port", Default: "8080"}, {Name: "env", Default: "dev"}},
+		Windows: []WindowConfig{
+			{Name: "{{.repo}}", Command: "nvim ."},
+			{
+				Name: "run",
+				Panes: []PaneConfig{
+					{Command: "PORT={{.port}} APP_ENV={{.env}} make run"},
+					{Command: "echo $SHELL on {{.branch}}", Path: "{{.path}}/logs"},
+				},
+			},
+		},
+	}
+
+	vars := template.Vars(BuiltinVars("api_2", "/src/api", "main"), map[string]string{"env": "staging"})
+	expanded := template.Expand(vars)
+
+	if expanded.FocusedWindow != "api" || expanded.Windows[0].Name != "api" {
+		t.Errorf("Expected repo name in window name and focus, got %+v", expanded)
+	}
+	if got := expanded.Windows[1].Panes[0].Command; got != "PORT=8080 APP_ENV=staging make run" {
+		t.Errorf("Unexpected pane command %q", got)
+	}
+	if got := expanded.Windows[1].Panes[1]; got.Command != "echo $SHELL on main" || got.Path != "/src/api/logs" {
+		t.Errorf("Unexpected pane %+v", got)
+	}
+	if template.Windows[1].Panes[0].Command != "PORT={{.port}} APP_ENV={{.env}} make run" {
+		t.Error("Expand modified the original template")
+	}
+}
+
+func TestExpandLeavesOtherTemplates(t *testing.T) {
+	template := &SessionTemplate{
+		Name: "ops",
+		Windows: []WindowConfig{
+			{Name: "containers", Command: "watch docker ps --format '{{.Names}}' --filter name={{.repo}}"},
+			{Name: "pods", Command: `kubectl get pods -o go-template='{{range .items}}{{.metadata.name}}{{"\n"}}{{end}}'`},
+			{Name: "typo", Command: "run {{.missing}}"},
+		},
+	}
+
+	expanded := template.Expand(template.Vars(BuiltinVars("api", "/src/api", "main"), nil))
+	want := []string{
+		"watch docker ps --format '{{.Names}}' --filter name=api",
+		`kubectl get pods -o go-template='{{range .items}}{{.metadata.name}}{{"\n"}}{{end}}'`,
+		"run {{.missing}}",
+	}
+	for i, window := range expanded.Windows {
+		if window.Command != want[i] {
+			t.Errorf("Expected %q, got %q", want[i], window.Command)
+		}
+	}
+}
+
+func TestExpandQuotesValues(t *testing.T) {
+	template := &SessionTemplate{
+		Name:   "review",
+		Params: []TemplateParam{{Name: "title"}},
+		Windows: []WindowConfig{
+			{Name: "{{.branch}}", Command: "git log {{.branch}} && echo {{.title}}", Path: "{{.path}}"},
+		},
+	}
+
+	vars := template.Vars(BuiltinVars("api", "/src/my api", "x;$(rm -rf ~)"), map[string]string{"title": "it's done"})
+	window := template.Expand(vars).Windows[0]
+	if want := `git log 'x;$(rm -rf ~)' && echo 'it'\''s done'`; window.Command != want {
+		t.Errorf("Expected the values to be quoted in the command, got %q", window.Command)
+	}
+	if window.Name != "x;$(rm -rf ~)" || window.Path != "/src/my api" {
+		t.Errorf("Expected names and paths to be left unquoted, got %+v", window)
+	}
+
+	for value, want := range map[string]string{"main": "main", "feature/login-2": "feature/login-2", "": "''", "a b": "'a b'"} {
+		if got := ShellQuote(value); got != want {
+			t.Errorf("ShellQuote(%q) = %s, want %s", value, got, want)
+		}
+	}
+}
+
+func TestCheckParams(t *testing.T) {
+	template := &SessionTemplate{Name: "service", Params: []TemplateParam{{Name: "port"}, {Name: "env", Default: "dev"}}}
+
+	if err := template.CheckParams(map[string]string{"port": "9000"}); err != nil {
+		t.Errorf("Expected the params to be accepted, got %v", err)
+	}
+	for _, values := range []map[string]string{
+		{},
+		{"port": ""},
+		{"port": "9000", "prot": "9001"},
+	} {
+		if err := template.CheckParams(values); err == nil {
+			t.Errorf("Expected %v to be rejected", values)
+		}
+	}
+}
+
+// isolateConfig points every config layer at empty temporary locations.
+// repoPaths lists the paths of the repository directories.
+func repoPaths(cfg *Config) []string {
+	var paths []string
+	for _, dir := range cfg.RepoDirectories {
+		paths = append(paths, dir.Path)
+	}
+	return paths
+}
+
+func isolateConfig(t *testing.T) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("MUXYARD_CONFIG", "")
+	saved := systemConfigPath
+	systemConfigPath = filepath.Join(t.TempDir(), "etc", "config.yaml")
+	t.Cleanup(func() { systemConfigPath = saved })
+}
+
+func loadTestConfig(t *testing.T, content string) (*Config, error) {
+	t.Helper()
+	isolateConfig(t)
+	path, err := configPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, path, content)
+	return Load()
+}
+
+// issueStrings returns the config's issues without the file name.
+func issueStrings(cfg *Config) []string {
+	var issues []string
+	for _, issue := range cfg.Issues {
+		issue.File = "config.yaml"
+		issues = append(issues, issue.String())
+	}
+	return issues
+}
+
+func windowNames(windows []WindowConfig) []string {
+	var names []string
+	for _, window := range windows {
+		names = append(names, window.Name)
+	}
+	return names
+}
+
+func TestTemplateInheritance(t *testing.T) {
+	cfg, err := loadTestConfig(t, `
+fragments:
+  database:
+    - name: db
+      command: psql
+templates:
+  - name: coding
+    description: Editor and shell
+    focused_window: editor
+    params:
+      - name: port
+        default: "3000"
+    windows:
+      - name: editor
+        command: nvim .
+      - name: server
+      - name: shell
+  - name: fullstack
+    extends: coding
+    include: [database]
+    remove: [server]
+    params:
+      - name: port
+        default: "8080"
+    windows:
+      - name: shell
+        command: make watch
+      - name: frontend
+        command: npm run dev
+  - name: golang
+    extends: fullstack
+    remove: [editor]
+`)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(cfg.Issues) != 0 {
+		t.Errorf("Unexpected issues: %q", issueStrings(cfg))
+	}
+
+	fullstack, err := cfg.GetTemplate("fullstack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := windowNames(fullstack.Windows), []string{"editor", "shell", "db", "frontend"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected windows %v, got %v", want, got)
+	}
+	if fullstack.Windows[1].Command != "make watch" {
+		t.Errorf("Expected shell to be overridden, got %+v", fullstack.Windows[1])
+	}
+	if fullstack.Description != "Editor and shell" || fullstack.FocusedWindow != "editor" {
+		t.Errorf("Expected description and focus to be inherited, got %+v", fullstack)
+	}
+	if len(fullstack.Params) != 1 || fullstack.Params[0].Default != "8080" {
+		t.Errorf("Expected the port default to be overridden, got %+v", fullstack.Params)
+	}
+	if fullstack.Extends != "" || fullstack.Include != nil || fullstack.Remove != nil {
+		t.Errorf("Expected a fully resolved template, got %+v", fullstack)
+	}
+
+	golang, _ := cfg.GetTemplate("golang")
+	if got, want := windowNames(golang.Windows), []string{"shell", "db", "frontend"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected windows %v, got %v", want, got)
+	}
+	if golang.FocusedWindow != "" {
+		t.Errorf("Expected focus on the removed editor to be dropped, got %q", golang.FocusedWindow)
+	}
+
+	coding, _ := cfg.GetTemplate("coding")
+	if len(coding.Windows) != 3 || coding.Windows[2].Command != "" {
+		t.Errorf("Base template was modified: %+v", coding.Windows)
+	}
+}
+
+func TestTemplateInheritanceErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{
+			name: "cycle",
+			config: `
+templates:
+  - name: a
+    extends: b
+  - name: b
+    extends: c
+  - name: c
+    extends: a
+`,
+			wantErr: "cycle: a -> b -> c -> a",
+		},
+		{
+			name: "self",
+			config: `
+templates:
+  - name: a
+    extends: a
+`,
+			wantErr: "cycle: a -> a",
+		},
+		{
+			name: "unknown base",
+			config: `
+templates:
+  - name: a
+    extends: missing
+`,
+			wantErr: `template "missing" not found`,
+		},
+		{
+			name: "unknown fragment",
+			config: `
+templates:
+  - name: a
+    include: [missing]
+`,
+			wantErr: `unknown fragment "missing"`,
+		},
+		{
+			name: "remove missing window",
+			config: `
+templates:
+  - name: a
+    windows: [{name: editor}]
+  - name: b
+    extends: a
+    remove: [shell]
+`,
+			wantErr: `removes window "shell"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadTestConfig(t, tt.config)
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if issues := issueStrings(cfg); !strings.Contains(strings.Join(issues, "\n"), tt.wantErr) {
+				t.Errorf("Expected an issue containing %q, got %q", tt.wantErr, issues)
+			}
+		})
+	}
+}
+
+func TestProjectTemplateExtends(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ProjectFile), `
+name: coding
+extends: coding
+windows:
+  - name: server
+    command: make run
+`)
+
+	template, issues, err := DefaultConfig().ProjectTemplate(dir)
+	if err != nil || len(issues) != 0 {
+		t.Fatalf("ProjectTemplate failed: %v (err %v)", issues, err)
+	}
+	if got, want := windowNames(template.Windows), []string{"editor", "server", "shell"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected windows %v, got %v", want, got)
+	}
+	if template.Windows[1].Command != "make run" || template.Source == "" {
+		t.Errorf("Unexpected project template %+v", template)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg, err := loadTestConfig(t, `repo_directories:
+  - ~/src
+templates:
+  - name: coding
+    focused_windw: editor
+    windows:
+      - name: editor
+  - name: coding
+    windows:
+      - name: shell
+  - name: empty
+    windows: []
+  - name: typo
+    focused_window: edtor
+    windows:
+      - name: editor
+        panes:
+          - command: nvim
+          - split: diagonal
+colours:
+  selected: red
+`)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	want := []string{
+		`config.yaml:5: unknown field "focused_windw" in template (did you mean "focused_window"?)`,
+		`config.yaml:20: unknown field "colours" in the config (did you mean "colors"?)`,
+		`config.yaml:8: duplicate template name "coding" (first defined on line 4)`,
+		`config.yaml:12: template "empty" has no windows`,
+		`config.yaml:19: template "typo": window "editor" pane 2: split must be horizontal, vertical, h, or v, got "diagonal"`,
+		`config.yaml:14: focused_window "edtor" names no window of template "typo"`,
+	}
+	if got := issueStrings(cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected issues:\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// The rest of the file is still used
+	if len(cfg.RepoDirectories) != 1 || len(cfg.Templates) != 4 {
+		t.Errorf("Expected the valid settings to load, got %+v", cfg)
+	}
+}
+
+func TestValidateInheritedSplits(t *testing.T) {
+	cfg, err := loadTestConfig(t, `fragments:
+  monitors:
+    - name: top
+      panes: [{command: htop}, {split: sideways}]
+templates:
+  - name: base
+    windows:
+      - name: editor
+        panes: [{command: nvim}, {split: h, size: 30%}, {split: v}]
+  - name: ops
+    extends: base
+    include: [monitors]
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The short forms are fine; the fragment's split is reported for the
+	// template that includes it
+	want := []string{`config.yaml:10: template "ops": window "top" pane 2: split must be horizontal, vertical, h, or v, got "sideways"`}
+	if got := issueStrings(cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestValidateSyntaxError(t *testing.T) {
+	cfg, err := loadTestConfig(t, "repo_directories:\n  - ~/src\ncolors: selected: red\n")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	issues := issueStrings(cfg)
+	if len(issues) != 1 || !strings.HasPrefix(issues[0], "config.yaml:3: ") || !strings.HasSuffix(issues[0], "ignoring this file") {
+		t.Errorf("Expected a single located syntax issue, got %q", issues)
+	}
+	if len(cfg.Templates) == 0 {
+		t.Error("Expected the default templates")
+	}
+}
+
+func TestLayers(t *testing.T) {
+	isolateConfig(t)
+	t.Setenv("HOME", "/home/me")
+	t.Setenv("WORK", "/srv/work")
+	dir, _ := configDir()
+
+	writeFile(t, systemConfigPath, `
+repo_directories: [/srv/shared, $WORK]
+templates:
+  - name: coding
+    windows: [{name: editor, command: nvim .}, {name: shell}]
+  - name: review
+    windows: [{name: diff, command: git diff}]
+colors:
+  selected: red
+  title: {foreground: white, background: blue}
+tmux:
+  servers: [{name: team, socket_name: team}]
+`)
+	writeFile(t, filepath.Join(dir, "config.yaml"), `
+repo_directories: [~/src, /srv/work]
+templates:
+  - name: coding
+    windows: [{name: editor, command: hx .}]
+  - name: notes
+    extends: review
+    windows: [{name: notes, path: ~/notes}]
+colors:
+  title: {background: green}
+`)
+	writeFile(t, filepath.Join(dir, "conf.d", "20-b.yaml"), "repo_directories: [/b]\n")
+	writeFile(t, filepath.Join(dir, "conf.d", "10-a.yaml"), "repo_directories: [/a]\ntmux: {socket_name: mine}\n")
+	override := filepath.Join(t.TempDir(), "override.yaml")
+	writeFile(t, override, "tmux:\n  servers: [{name: team, socket_path: $WORK/tmux.sock}]\n")
+	t.Setenv("MUXYARD_CONFIG", override)
+
+	files, err := Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{systemConfigPath, filepath.Join(dir, "config.yaml"), filepath.Join(dir, "conf.d", "10-a.yaml"), filepath.Join(dir, "conf.d", "20-b.yaml"), override}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("Expected files %v, got %v", want, files)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Issues) != 0 {
+		t.Errorf("Unexpected issues: %q", issueStrings(cfg))
+	}
+
+	if want := []string{"/srv/shared", "/srv/work", "/home/me/src", "/a", "/b"}; !reflect.DeepEqual(repoPaths(cfg), want) {
+		t.Errorf("Expected repo directories %v, got %v", want, cfg.RepoDirectories)
+	}
+
+	var names []string
+	for _, template := range cfg.Templates {
+		names = append(names, template.Name)
+	}
+	if want := []string{"coding", "review", "notes"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Expected templates %v, got %v", want, names)
+	}
+	if coding := cfg.Templates[0]; len(coding.Windows) != 1 || coding.Windows[0].Command != "hx ." {
+		t.Errorf("Expected the user's coding template to replace the system one, got %+v", coding)
+	}
+	if notes := cfg.Templates[2]; windowNames(notes.Windows)[0] != "diff" || notes.Windows[1].Path != "/home/me/notes" {
+		t.Errorf("Expected notes to extend the system template with an expanded path, got %+v", notes)
+	}
+
+	if cfg.Colors.Selected != "red" || cfg.Colors.Title != (ColorPair{Foreground: "white", Background: "green"}) {
+		t.Errorf("Expected colors merged field by field, got %+v", cfg.Colors)
+	}
+	if cfg.Tmux.SocketName != "mine" {
+		t.Errorf("Expected the drop-in socket name, got %q", cfg.Tmux.SocketName)
+	}
+	if want := []ServerConfig{{Name: "team", SocketPath: "/srv/work/tmux.sock"}}; !reflect.DeepEqual(cfg.Tmux.Servers, want) {
+		t.Errorf("Expected servers %+v, got %+v", want, cfg.Tmux.Servers)
+	}
+}
+
+func TestLayerIssuesKeepTheirFile(t *testing.T) {
+	isolateConfig(t)
+	dir, _ := configDir()
+	writeFile(t, systemConfigPath, "templates:\n  - name: base\n    windows: [{name: a}]\n")
+	writeFile(t, filepath.Join(dir, "config.yaml"), "templates:\n  - name: mine\n    extends: bsae\n")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Issues) != 1 || cfg.Issues[0].File != filepath.Join(dir, "config.yaml") || cfg.Issues[0].Line != 2 {
+		t.Errorf("Expected one issue on line 2 of the user config, got %+v", cfg.Issues)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "conf.d")); !os.IsNotExist(err) {
+		t.Error("Load should not create anything when a config exists")
+	}
+}
+
+func TestLoadWritesDefaultOnlyWithoutLayers(t *testing.T) {
+	isolateConfig(t)
+	dir, _ := configDir()
+	writeFile(t, systemConfigPath, "repo_directories: [/srv]\n")
+
+	if _, err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "config.yaml")); !os.IsNotExist(err) {
+		t.Error("Expected no user config to be written when a system config exists")
+	}
+
+	os.Remove(systemConfigPath)
+	if _, err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "config.yaml")); err != nil {
+		t.Errorf("Expected the default config to be written: %v", err)
+	}
+}
+
+const unversionedConfig = `# Shared repositories
+repo_directories:
+  - ~/src # personal
+  - ~/work
+
+templates:
+  # The one I use every day
+  - name: coding
+    windows:
+      - name: editor
+        command: nvim .
+`
+
+func TestMigrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, unversionedConfig)
+
+	cfg, err := LoadFiles(path)
+	if err != nil {
+		t.Fatalf("LoadFiles failed: %v", err)
+	}
+	if len(cfg.Issues) != 0 || cfg.Version != Version || len(cfg.Templates) != 1 {
+		t.Errorf("Expected the unversioned config to load as version %d, got %+v", Version, cfg)
+	}
+
+	migrated, err := Migrate(path)
+	if err != nil || !migrated {
+		t.Fatalf("Expected the file to be migrated, got %v (err %v)", migrated, err)
+	}
+
+	backup, _ := os.ReadFile(path + ".bak")
+	if string(backup) != unversionedConfig {
+		t.Errorf("Expected the original in the backup, got:\n%s", backup)
+	}
+	data, _ := os.ReadFile(path)
+	for _, want := range []string{"version: 2\n", "# Shared repositories", "~/src # personal", "# The one I use every day"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected %q in the migrated file:\n%s", want, data)
+		}
+	}
+
+	if migrated, err := Migrate(path); err != nil || migrated {
+		t.Errorf("Expected an up to date file to be left alone, got %v (err %v)", migrated, err)
+	}
+}
+
+func TestMigrateDropsDefaultColors(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, "legacy.yaml")
+	writeFile(t, legacy, `version: 1
+colors:
+  title:
+    foreground: "#FAFAFA"
+    background: "#7D56F4"
+  selected: "#EE6FF8"
+  dimmed: "#626262"
+  help: "#626262"
+  error: "#FF0000"
+  success: "#00FF00"
+  border: "#874BFD"
+  input: "#874BFD"
+  focused_input: "#FF75B7"
+  spinner: "205"
+  highlight: "#FF75B7"
+  filter_border: "#FF75B7"
+`)
+	custom := filepath.Join(dir, "custom.yaml")
+	writeFile(t, custom, "version: 1\ncolors:\n  selected: red\n")
+
+	cfg, err := LoadFiles(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Colors != (ColorConfig{}) {
+		t.Errorf("Expected the old default colors to make way for the theme, got %+v", cfg.Colors)
+	}
+
+	cfg, err = LoadFiles(custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Colors != (ColorConfig{Selected: "red"}) {
+		t.Errorf("Expected customized colors to be kept, got %+v", cfg.Colors)
+	}
+}
+
+func TestUnknownTheme(t *testing.T) {
+	cfg, err := loadTestConfig(t, "repo_directories: []\ntheme: solarised\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`config.yaml:2: unknown theme "solarised" (available: default, dark, light, monochrome, solarized, catppuccin)`}
+	if got := issueStrings(cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestRepoDirectoryRules(t *testing.T) {
+	cfg, err := loadTestConfig(t, `repo_directories:
+  - /srv/src
+  - path: /srv/mono
+    max_depth: 6
+    exclude: [node_modules, "[target"]
+    include: ["services/*"]
+    follow_symlinks: true
+  - path: /srv/work
+    max_deph: 2
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []RepoDirectory{
+		{Path: "/srv/src"},
+		{Path: "/srv/mono", MaxDepth: 6, Exclude: []string{"node_modules", "[target"}, Include: []string{"services/*"}, FollowSymlinks: true},
+		{Path: "/srv/work"},
+	}
+	if !reflect.DeepEqual(cfg.RepoDirectories, want) {
+		t.Errorf("Expected repo directories %+v, got %+v", want, cfg.RepoDirectories)
+	}
+	if depth, exclude := cfg.RepoDirectories[0].Depth(), cfg.RepoDirectories[0].Excluded(); depth != DefaultMaxDepth || !reflect.DeepEqual(exclude, DefaultExclude) {
+		t.Errorf("Expected the default rules for a plain path, got depth %d and exclude %q", depth, exclude)
+	}
+
+	wantIssues := []string{
+		`config.yaml:9: unknown field "max_deph" in repo_directories entry (did you mean "max_depth"?)`,
+		`config.yaml:5: invalid exclude glob "[target"`,
+	}
+	if got := issueStrings(cfg); !reflect.DeepEqual(got, wantIssues) {
+		t.Errorf("Expected %q, got %q", wantIssues, got)
+	}
+}
+
+func TestZoxideLayers(t *testing.T) {
+	isolateConfig(t)
+	dir, _ := configDir()
+	writeFile(t, systemConfigPath, "zoxide: {enabled: true, min_score: 5}\n")
+	writeFile(t, filepath.Join(dir, "config.yaml"), "zoxide:\n  min_score: -1\n  minscore: 2\n")
+	writeFile(t, filepath.Join(dir, "conf.d", "10-off.yaml"), "zoxide: {enabled: false}\n")
+
+	files, err := Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadFiles(files[:2]...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Zoxide.On() || cfg.Zoxide.MinScore != -1 {
+		t.Errorf("Expected zoxide to stay on with the user's min_score, got %+v", cfg.Zoxide)
+	}
+	wantIssues := []string{
+		`config.yaml:3: unknown field "minscore" in zoxide (did you mean "min_score"?)`,
+		`config.yaml:2: min_score must not be negative, got -1`,
+	}
+	if got := issueStrings(cfg); !reflect.DeepEqual(got, wantIssues) {
+		t.Errorf("Expected %q, got %q", wantIssues, got)
+	}
+
+	cfg, err = LoadFiles(files...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Zoxide.On() {
+		t.Error("Expected a later file to turn zoxide off")
+	}
+}
+
+func TestNewerConfigVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, "version: 99\nrepo_directories: []\n")
+
+	_, err := LoadFiles(path)
+	if err == nil || !strings.Contains(err.Error(), "please upgrade muxyard") {
+		t.Errorf("Expected an error asking to upgrade, got %v", err)
+	}
+	if _, err := Migrate(path); err == nil {
+		t.Error("Expected Migrate to refuse a newer file")
+	}
+}
+
+func TestSaveRoundTrip(t *testing.T) {
+	isolateConfig(t)
+	path, _ := configPath()
+	dir, _ := configDir()
+	user := unversionedConfig + `
+  # Coding with a test watcher
+  - name: testing
+    extends: coding
+    windows:
+      - name: tests
+        command: go test ./...
+
+keys:
+  delete: D # not d, too easy to hit
+  rescan: []
+`
+	writeFile(t, path, user)
+	writeFile(t, filepath.Join(dir, "conf.d", "work.yaml"), `
+repo_directories:
+  - ~/work/clients
+templates:
+  - name: client
+    windows:
+      - name: main
+theme: dark
+`)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	// yaml.v3 doesn't keep blank lines; everything else is to come back
+	data, _ := os.ReadFile(path)
+	if want := "version: 2\n" + strings.ReplaceAll(user, "\n\n", "\n"); string(data) != want {
+		t.Errorf("Expected the user file to come back as written, got:\n%s", data)
+	}
+
+	cfg.User().Templates[0].Windows[0].Command = "hx ."
+	cfg.User().Templates = append(cfg.User().Templates, SessionTemplate{Name: "notes", Windows: []WindowConfig{{Name: "notes"}}})
+	if err := Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ = os.ReadFile(path)
+	for _, want := range []string{"# Shared repositories", "~/src # personal", "# The one I use every day", "command: hx .", "extends: coding", "name: notes"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected %q in the saved file:\n%s", want, data)
+		}
+	}
+	for _, unwanted := range []string{"clients", "client", "dark", "command: nvim ."} {
+		if strings.Contains(string(data), unwanted) {
+			t.Errorf("Expected no %q in the saved file:\n%s", unwanted, data)
+		}
+	}
+
+	saved, err := Load()
+	if err != nil || len(saved.Issues) != 0 || len(saved.Templates) != 4 || saved.Keys.Rescan == nil {
+		t.Errorf("Expected the saved file to load cleanly, got %+v (err %v)", saved, err)
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	isolateConfig(t)
+	dir, _ := configDir()
+	watcher := NewWatcher()
+	if watcher.Changed() {
+		t.Error("Expected no change right after creating the watcher")
+	}
+
+	writeFile(t, filepath.Join(dir, "config.yaml"), "repo_directories: [/a]\n")
+	if !watcher.Changed() {
+		t.Error("Expected a new config file to be a change")
+	}
+	if watcher.Changed() {
+		t.Error("Expected a change to be reported once")
+	}
+
+	writeFile(t, filepath.Join(dir, "config.yaml"), "repo_directories: [/a, /b]\n")
+	if !watcher.Changed() {
+		t.Error("Expected an edit to be a change")
+	}
+
+	writeFile(t, filepath.Join(dir, "conf.d", "extra.yaml"), "repo_directories: [/c]\n")
+	if !watcher.Changed() {
+		t.Error("Expected a new drop-in to be a change")
+	}
+
+	cfg, err := Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"/a", "/b", "/c"}; !reflect.DeepEqual(repoPaths(cfg), want) {
+		t.Errorf("Expected repo directories %v, got %v", want, cfg.RepoDirectories)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	isolateConfig(t)
+	dir, _ := configDir()
+	writeFile(t, systemConfigPath, "keys:\n  create: a\n  delete: [d, x]\n")
+	writeFile(t, filepath.Join(dir, "config.yaml"), "keys:\n  delete: D\n  preview: []\n  quti: Q\n")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := KeysConfig{Create: KeyList{"a"}, Delete: KeyList{"D"}, Preview: KeyList{}}
+	if !reflect.DeepEqual(cfg.Keys, want) {
+		t.Errorf("Expected keys %+v, got %+v", want, cfg.Keys)
+	}
+	if issues := issueStrings(cfg); len(issues) != 1 || !strings.HasSuffix(issues[0], `unknown field "quti" in keys (did you mean "quit"?)`) {
+		t.Errorf("Expected the misspelt key to be reported, got %q", issues)
+	}
+}
+
+func TestWorktreeDir(t *testing.T) {
+	t.Setenv("HOME", "/home/me")
+	tests := []struct {
+		path     string
+		repo     string
+		expected string
+	}{
+		{"", "/src/api", "/src/api-feature-login"},
+		{"", "/srv/git/api.git", "/srv/git/api-feature-login"},
+		{".worktrees/{{.branch}}", "/src/api", "/src/api/.worktrees/feature-login"},
+		{"~/wt/{{.repo}}/{{.branch}}", "/src/api", "/home/me/wt/api/feature-login"},
+	}
+	for _, tt := range tests {
+		cfg := &Config{Worktrees: WorktreeConfig{Path: tt.path}}
+		dir, err := cfg.WorktreeDir(tt.repo, "feature/login")
+		if err != nil || dir != tt.expected {
+			t.Errorf("WorktreeDir(%q) with path %q = %q (err %v), want %q", tt.repo, tt.path, dir, err, tt.expected)
+		}
+	}
+
+	cfg := &Config{Worktrees: WorktreeConfig{Path: "{{.ticket}}"}}
+	if _, err := cfg.WorktreeDir("/src/api", "login"); err == nil {
+		t.Error("Expected an error for an unknown variable")
+	}
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// BuiltinVars returns the variables available to every template: the
+// session name, the session directory, its base name as repo, and the
+// current git branch (empty outside a repository).
+func BuiltinVars(session, path, branch string) map[string]string {
+	return map[string]string{
+		"session": session,
+		"path":    path,
+		"repo":    filepath.Base(path),
+		"branch":  branch,
+	}
+}
+
+// Vars merges the template's parameter defaults, the given parameter values,
+// and the built-in variables, in increasing order of precedence.
+func (t *SessionTemplate) Vars(builtins, params map[string]string) map[string]string {
+	vars := make(map[string]string, len(builtins)+len(t.Params))
+	for _, param := range t.Params {
+		vars[param.Name] = param.Default
+	}
+	for name, value := range params {
+		vars[name] = value
+	}
+	for name, value := range builtins {
+		vars[name] = value
+	}
+	return vars
+}
+
+// Expand returns a copy of the template with the variables referenced in
+// window names, commands, and paths filled in, e.g. "npm run dev -- --port
+// {{.port}}". Only the variables in vars are expanded; other {{...}} text,
+// such as docker ps --format '{{.Names}}', is left for the command. Values
+// are shell-quoted in commands when they aren't plain words, so that a
+// branch or parameter can't inject shell syntax.
+func (t *SessionTemplate) Expand(vars map[string]string) *SessionTemplate {
+	expanded := *t
+	expanded.Windows = make([]WindowConfig, len(t.Windows))
+
+	plain := func(text string) string {
+		result, _ := substitute(text, vars, func(value string) string { return value })
+		return result
+	}
+	command := func(text string) string {
+		result, _ := substitute(text, vars, ShellQuote)
+		return result
+	}
+
+	expanded.FocusedWindow = plain(t.FocusedWindow)
+	for i, window := range t.Windows {
+		window.Name = plain(window.Name)
+		window.Command = command(window.Command)
+		window.Path = plain(window.Path)
+
+		window.Panes = append([]PaneConfig(nil), window.Panes...)
+		for j := range window.Panes {
+			pane := &window.Panes[j]
+			pane.Command = command(pane.Command)
+			pane.Path = plain(pane.Path)
+		}
+		expanded.Windows[i] = window
+	}
+	return &expanded
+}
+
+// CheckParams reports parameter values the template doesn't declare, and
+// declared parameters without a default that are left empty.
+func (t *SessionTemplate) CheckParams(values map[string]string) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !slices.ContainsFunc(t.Params, func(p TemplateParam) bool { return p.Name == name }) {
+			return fmt.Errorf("template %q has no parameter %q", t.Name, name)
+		}
+	}
+
+	for _, param := range t.Params {
+		if param.Required() && values[param.Name] == "" {
+			return fmt.Errorf("parameter %s has no default and needs a value", param.Name)
+		}
+	}
+	return nil
+}
+
+// Required reports whether the parameter needs a value, having no default.
+func (p TemplateParam) Required() bool {
+	return p.Default == ""
+}
+
+// WorktreeDir returns the directory for a new worktree of the repository
+// at repoPath. Slashes in the branch name become dashes, so that
+// feature/login doesn't nest directories.
+func (c *Config) WorktreeDir(repoPath, branch string) (string, error) {
+	pattern := c.Worktrees.Path
+	if pattern == "" {
+		pattern = DefaultWorktreePath
+	}
+	dir, unknown := substitute(pattern, map[string]string{
+		"repo":   strings.TrimSuffix(filepath.Base(repoPath), ".git"),
+		"branch": strings.ReplaceAll(branch, "/", "-"),
+	}, func(value string) string { return value })
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("worktrees: path: unknown variable %q (available: repo, branch)", unknown[0])
+	}
+	dir = ExpandPath(dir)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(repoPath, dir)
+	}
+	return filepath.Clean(dir), nil
+}
+
+// varRef matches a variable reference such as {{.port}} or {{ .port }}.
+var varRef = regexp.MustCompile(`\{\{\s*\.([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// substitute replaces the references to vars in text with their values,
+// passed through quote. References to other names are left as written and
+// returned as unknown.
+func substitute(text string, vars map[string]string, quote func(string) string) (result string, unknown []string) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	result = varRef.ReplaceAllStringFunc(text, func(ref string) string {
+		name := varRef.FindStringSubmatch(ref)[1]
+		value, ok := vars[name]
+		if !ok {
+			unknown = append(unknown, name)
+			return ref
+		}
+		return quote(value)
+	})
+	return result, unknown
+}
+
+// ShellQuote quotes a value for sh unless it is a plain word, made only of
+// characters the shell gives no special meaning.
+func ShellQuote(value string) string {
+	if value != "" && strings.Trim(value, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-+=.,/:@%") == "" {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// templateResolver merges templates with the templates they extend and the
+// fragments they include. Resolved templates are cached by name, and the
+// chain of templates being resolved is kept to report cycles.
+type templateResolver struct {
+	templates map[string]SessionTemplate
+	fragments map[string][]WindowConfig
+	resolved  map[string]SessionTemplate
+	chain     []string
+}
+
+func newTemplateResolver(c *Config) *templateResolver {
+	r := &templateResolver{
+		templates: make(map[string]SessionTemplate, len(c.Templates)),
+		fragments: c.Fragments,
+		resolved:  make(map[string]SessionTemplate),
+	}
+	// Like GetTemplate, the first template with a name wins
+	for _, template := range c.Templates {
+		if _, ok := r.templates[template.Name]; !ok {
+			r.templates[template.Name] = template
+		}
+	}
+	return r
+}
+
+// resolveTemplates replaces every configured template with its fully merged
+// form, so callers never have to deal with extends or include. It returns
+// one error per template; templates that fail to resolve are left as
+// written.
+func (c *Config) resolveTemplates() []error {
+	r := newTemplateResolver(c)
+	errs := make([]error, len(c.Templates))
+	resolved := make([]SessionTemplate, len(c.Templates))
+	for i, template := range c.Templates {
+		r.chain = []string{template.Name}
+		if resolved[i], errs[i] = r.resolve(template); errs[i] != nil {
+			resolved[i] = template
+		}
+	}
+	c.Templates = resolved
+	return errs
+}
+
+// ResolveTemplate merges a template that isn't part of the config, such as a
+// project template, with the configured templates and fragments it uses.
+func (c *Config) ResolveTemplate(template *SessionTemplate) (*SessionTemplate, error) {
+	resolved, err := newTemplateResolver(c).resolve(*template)
+	if err != nil {
+		return nil, err
+	}
+	return &resolved, nil
+}
+
+func (r *templateResolver) lookup(name string) (SessionTemplate, error) {
+	if template, ok := r.resolved[name]; ok {
+		return template, nil
+	}
+	for i, n := range r.chain {
+		if n == name {
+			cycle := append(append([]string(nil), r.chain[i:]...), name)
+			return SessionTemplate{}, fmt.Errorf("template inheritance cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	template, ok := r.templates[name]
+	if !ok {
+		return SessionTemplate{}, fmt.Errorf("template %q not found", name)
+	}
+
+	r.chain = append(r.chain, name)
+	resolved, err := r.resolve(template)
+	r.chain = r.chain[:len(r.chain)-1]
+	if err != nil {
+		return SessionTemplate{}, err
+	}
+
+	r.resolved[name] = resolved
+	return resolved, nil
+}
+
+// resolve builds a template's windows from its base, then its included
+// fragments, minus the removed windows, with its own windows replacing
+// inherited ones of the same name and the rest appended.
+func (r *templateResolver) resolve(template SessionTemplate) (SessionTemplate, error) {
+	if template.Extends == "" && len(template.Include) == 0 && len(template.Remove) == 0 {
+		return template, nil
+	}
+
+	var windows []WindowConfig
+	if template.Extends != "" {
+		base, err := r.lookup(template.Extends)
+		if err != nil {
+			return SessionTemplate{}, fmt.Errorf("template %q extends %q: %w", template.Name, template.Extends, err)
+		}
+		windows = append(windows, base.Windows...)
+		if template.Description == "" {
+			template.Description = base.Description
+		}
+		if template.FocusedWindow == "" {
+			template.FocusedWindow = base.FocusedWindow
+		}
+		template.Params = mergeParams(base.Params, template.Params)
+	}
+
+	for _, name := range template.Include {
+		fragment, ok := r.fragments[name]
+		if !ok {
+			return SessionTemplate{}, fmt.Errorf("template %q includes unknown fragment %q", template.Name, name)
+		}
+		windows = append(windows, fragment...)
+	}
+
+	for _, name := range template.Remove {
+		i := windowIndex(windows, name)
+		if i < 0 {
+			return SessionTemplate{}, fmt.Errorf("template %q removes window %q, which it does not inherit", template.Name, name)
+		}
+		windows = append(windows[:i:i], windows[i+1:]...)
+	}
+
+	for _, window := range template.Windows {
+		if i := windowIndex(windows, window.Name); window.Name != "" && i >= 0 {
+			windows[i] = window
+		} else {
+			windows = append(windows, window)
+		}
+	}
+
+	// An inherited focus on a window that was removed would fail to select
+	if template.FocusedWindow != "" && !strings.Contains(template.FocusedWindow, "{{") &&
+		windowIndex(windows, template.FocusedWindow) < 0 {
+		template.FocusedWindow = ""
+	}
+
+	template.Windows = windows
+	template.Extends = ""
+	template.Include = nil
+	template.Remove = nil
+	return template, nil
+}
+
+func windowIndex(windows []WindowConfig, name string) int {
+	for i, window := range windows {
+		if window.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// mergeParams returns the base parameters with any the child redeclares
+// replaced, followed by the child's new ones.
+func mergeParams(base, child []TemplateParam) []TemplateParam {
+	merged := append([]TemplateParam(nil), base...)
+	for _, param := range child {
+		replaced := false
+		for i := range merged {
+			if merged[i].Name == param.Name {
+				merged[i] = param
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, param)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,222 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// systemConfigPath is the config shared by every user of the machine.
+var systemConfigPath = "/etc/muxyard/config.yaml"
+
+// Files returns the config files that exist, in the order they are merged:
+// the system config, the user config, the user's conf.d/*.yaml drop-ins
+// sorted by name, and the file named by $MUXYARD_CONFIG.
+func Files() ([]string, error) {
+	var files []string
+	exists := func(path string) bool {
+		info, err := os.Stat(path)
+		return err == nil && !info.IsDir()
+	}
+
+	if exists(systemConfigPath) {
+		files = append(files, systemConfigPath)
+	}
+
+	dir, err := configDir()
+	if err != nil {
+		return nil, err
+	}
+	if path := filepath.Join(dir, "config.yaml"); exists(path) {
+		files = append(files, path)
+	}
+
+	dropIns, err := filepath.Glob(filepath.Join(dir, "conf.d", "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(dropIns)
+	files = append(files, dropIns...)
+
+	if path := os.Getenv("MUXYARD_CONFIG"); path != "" {
+		path = ExpandPath(path)
+		if !exists(path) {
+			return nil, fmt.Errorf("MUXYARD_CONFIG: %s does not exist", path)
+		}
+		files = append(files, path)
+	}
+
+	return files, nil
+}
+
+// LoadFiles merges the given config files, each overriding the ones before
+// it, and validates the result. Problems with their contents are reported
+// in the config's Issues rather than as an error.
+func LoadFiles(paths ...string) (*Config, error) {
+	cfg := &Config{user: &Config{}}
+	var origins []templateOrigin
+	loaded := 0
+	userPath, _ := configPath()
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		layer, root, issues, err := decode(path, data)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Issues = append(cfg.Issues, issues...)
+		if layer == nil {
+			continue
+		}
+		loaded++
+		cfg.Issues = append(cfg.Issues, layer.checkFile(path, root)...)
+
+		// Save writes back the user file's settings as they were written,
+		// so they are decoded once more before anything below changes them
+		if path == userPath {
+			cfg.user = &Config{}
+			_ = root.Decode(cfg.user) // Type errors are among the issues already
+		}
+
+		layer.expandPaths()
+		for i, template := range layer.Templates {
+			origin := templateOrigin{file: path, root: root, path: []any{"templates", i}}
+			// A later file replaces an earlier file's template of the same
+			// name; duplicates within one file are kept for checkFile to flag
+			j := slices.IndexFunc(cfg.Templates, func(t SessionTemplate) bool { return t.Name == template.Name })
+			if j >= 0 && origins[j].file != path {
+				cfg.Templates[j], origins[j] = template, origin
+			} else {
+				cfg.Templates = append(cfg.Templates, template)
+				origins = append(origins, origin)
+			}
+		}
+		cfg.merge(layer)
+	}
+
+	// Nothing to go on; fall back to the defaults, keeping the issues
+	if loaded == 0 && len(paths) > 0 {
+		issues, user := cfg.Issues, cfg.user
+		cfg = DefaultConfig()
+		cfg.Issues, cfg.user = issues, user
+		return cfg, nil
+	}
+
+	cfg.Version = Version
+	cfg.Issues = append(cfg.Issues, cfg.checkTemplates(origins)...)
+	return cfg, nil
+}
+
+// merge applies everything but the templates of a later layer: repository
+// directories are appended, skipping paths already listed, fragments and tmux servers
+// are replaced by name, and the project markers, theme, colors, keys,
+// worktree and zoxide settings, and sockets are overridden when set.
+func (c *Config) merge(layer *Config) {
+	for _, dir := range layer.RepoDirectories {
+		if !slices.ContainsFunc(c.RepoDirectories, func(d RepoDirectory) bool { return d.Path == dir.Path }) {
+			c.RepoDirectories = append(c.RepoDirectories, dir)
+		}
+	}
+
+	for name, windows := range layer.Fragments {
+		if c.Fragments == nil {
+			c.Fragments = make(map[string][]WindowConfig)
+		}
+		c.Fragments[name] = windows
+	}
+
+	if layer.ProjectMarkers != nil {
+		c.ProjectMarkers = layer.ProjectMarkers
+	}
+	if layer.Theme != "" {
+		c.Theme = layer.Theme
+	}
+	overrideFields(reflect.ValueOf(&c.Colors).Elem(), reflect.ValueOf(layer.Colors))
+	overrideFields(reflect.ValueOf(&c.Keys).Elem(), reflect.ValueOf(layer.Keys))
+	overrideFields(reflect.ValueOf(&c.Worktrees).Elem(), reflect.ValueOf(layer.Worktrees))
+	overrideFields(reflect.ValueOf(&c.Zoxide).Elem(), reflect.ValueOf(layer.Zoxide))
+
+	// The socket name and path are alternatives, so a layer sets both
+	if layer.Tmux.SocketName != "" || layer.Tmux.SocketPath != "" {
+		c.Tmux.SocketName = layer.Tmux.SocketName
+		c.Tmux.SocketPath = layer.Tmux.SocketPath
+	}
+	for _, server := range layer.Tmux.Servers {
+		i := slices.IndexFunc(c.Tmux.Servers, func(s ServerConfig) bool { return s.Name == server.Name })
+		if i >= 0 {
+			c.Tmux.Servers[i] = server
+		} else {
+			c.Tmux.Servers = append(c.Tmux.Servers, server)
+		}
+	}
+}
+
+// overrideFields sets every string, number, slice, and pointer field of
+// dst, including those of nested structs, that is set in src. An empty but
+// non-nil slice counts as set.
+func overrideFields(dst, src reflect.Value) {
+	for i := 0; i < dst.NumField(); i++ {
+		switch field := dst.Field(i); field.Kind() {
+		case reflect.String:
+			if value := src.Field(i).String(); value != "" {
+				field.SetString(value)
+			}
+		case reflect.Float64:
+			if value := src.Field(i).Float(); value != 0 {
+				field.SetFloat(value)
+			}
+		case reflect.Slice, reflect.Pointer:
+			if !src.Field(i).IsNil() {
+				field.Set(src.Field(i))
+			}
+		case reflect.Struct:
+			overrideFields(field, src.Field(i))
+		}
+	}
+}
+
+// expandPaths expands environment variables and ~ in every path setting.
+func (c *Config) expandPaths() {
+	for i, dir := range c.RepoDirectories {
+		c.RepoDirectories[i].Path = ExpandPath(dir.Path)
+	}
+	for i := range c.Templates {
+		expandWindowPaths(c.Templates[i].Windows)
+	}
+	for _, windows := range c.Fragments {
+		expandWindowPaths(windows)
+	}
+
+	c.Tmux.SocketPath = ExpandPath(c.Tmux.SocketPath)
+	for i := range c.Tmux.Servers {
+		c.Tmux.Servers[i].SocketPath = ExpandPath(c.Tmux.Servers[i].SocketPath)
+	}
+}
+
+func expandWindowPaths(windows []WindowConfig) {
+	for i := range windows {
+		windows[i].Path = ExpandPath(windows[i].Path)
+		for j := range windows[i].Panes {
+			windows[i].Panes[j].Path = ExpandPath(windows[i].Panes[j].Path)
+		}
+	}
+}
+
+// ExpandPath expands $VAR and ${VAR} references and a leading ~ in a path.
+// Template variables such as {{.path}} are left for Expand.
+func ExpandPath(path string) string {
+	path = os.ExpandEnv(path)
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = home + path[1:]
+		}
+	}
+	return path
+}
@@ -0,0 +1,243 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the config schema written by this build. Files without a
+// version key are version 0.
+const Version = 2
+
+// migrations[n] upgrades a config document from version n to n+1 by
+// editing its node tree, so comments and formatting survive a rewrite.
+var migrations = []func(doc *yaml.Node) error{
+	// Version 0 is every config written before versioning, which has the
+	// same shape as version 1
+	func(doc *yaml.Node) error { return nil },
+
+	// Version 2 introduced themes. Earlier files spell out the old default
+	// colors, which would override any theme, so those are dropped in
+	// favor of the default theme, which looks the same
+	func(doc *yaml.Node) error {
+		colors := mappingValue(doc, "colors")
+		if colors == nil {
+			return nil
+		}
+		var written ColorConfig
+		if err := colors.Decode(&written); err == nil && written == legacyDefaultColors {
+			removeMappingKey(doc, "colors")
+		}
+		return nil
+	},
+}
+
+// legacyDefaultColors are the colors written to new config files before
+// version 2.
+var legacyDefaultColors = ColorConfig{
+	Title:        ColorPair{Foreground: "#FAFAFA", Background: "#7D56F4"},
+	Selected:     "#EE6FF8",
+	Dimmed:       "#626262",
+	Help:         "#626262",
+	Error:        "#FF0000",
+	Success:      "#00FF00",
+	Border:       "#874BFD",
+	Input:        "#874BFD",
+	FocusedInput: "#FF75B7",
+	Spinner:      "205",
+	Highlight:    "#FF75B7",
+	FilterBorder: "#FF75B7",
+}
+
+// upgrade migrates a config document to the current version and returns
+// the version it had. Documents newer than this build are an error rather
+// than being misread.
+func upgrade(root *yaml.Node) (int, error) {
+	doc := root
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		doc = doc.Content[0]
+	}
+	if doc.Kind != yaml.MappingNode {
+		// Nothing to migrate; decoding reports the wrong shape
+		return Version, nil
+	}
+
+	version := 0
+	if node := mappingValue(doc, "version"); node != nil {
+		v, err := strconv.Atoi(node.Value)
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("line %d: invalid config version %q", node.Line, node.Value)
+		}
+		version = v
+	}
+	if version > Version {
+		return version, fmt.Errorf("config version %d is newer than this muxyard supports (%d); please upgrade muxyard", version, Version)
+	}
+
+	for v := version; v < Version; v++ {
+		if err := migrations[v](doc); err != nil {
+			return version, fmt.Errorf("failed to migrate from version %d: %w", v, err)
+		}
+	}
+	setMappingValue(doc, "version", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(Version)})
+
+	return version, nil
+}
+
+// Migrate upgrades the config file at path to the current version. The
+// original is kept next to it with a .bak suffix. It reports whether the
+// file needed migrating.
+func Migrate(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(root.Content) == 0 {
+		return false, nil
+	}
+
+	version, err := upgrade(&root)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", path, err)
+	}
+	if version == Version {
+		return false, nil
+	}
+
+	if err := os.WriteFile(path+".bak", data, 0644); err != nil {
+		return false, err
+	}
+	migrated, err := encodeNode(&root)
+	if err != nil {
+		return false, err
+	}
+	return true, os.WriteFile(path, migrated, 0644)
+}
+
+func encodeNode(root *yaml.Node) ([]byte, error) {
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue replaces the value of key, keeping its comments, or adds
+// the key at the top of the mapping.
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			value.LineComment = mapping.Content[i+1].LineComment
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	mapping.Content = append([]*yaml.Node{keyNode, value}, mapping.Content...)
+}
+
+func removeMappingKey(mapping *yaml.Node, key string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// mergeNodes makes dst hold the values of src while keeping dst's comments
+// and style wherever the two have the same shape. Mapping keys keep their
+// order, and sequence items are matched by name when they have one.
+func mergeNodes(dst, src *yaml.Node) {
+	if dst.Kind != src.Kind || dst.Kind == yaml.AliasNode {
+		head, line, foot := dst.HeadComment, dst.LineComment, dst.FootComment
+		*dst = *src
+		dst.HeadComment, dst.LineComment, dst.FootComment = head, line, foot
+		return
+	}
+
+	switch dst.Kind {
+	case yaml.ScalarNode:
+		dst.Value = src.Value
+		dst.Tag = src.Tag
+
+	case yaml.MappingNode:
+		var content []*yaml.Node
+		for i := 0; i+1 < len(dst.Content); i += 2 {
+			if value := mappingValue(src, dst.Content[i].Value); value != nil {
+				mergeNodes(dst.Content[i+1], value)
+				content = append(content, dst.Content[i], dst.Content[i+1])
+			}
+		}
+		for i := 0; i+1 < len(src.Content); i += 2 {
+			if mappingValue(dst, src.Content[i].Value) == nil {
+				content = append(content, src.Content[i], src.Content[i+1])
+			}
+		}
+		dst.Content = content
+
+	case yaml.SequenceNode:
+		content := make([]*yaml.Node, len(src.Content))
+		used := make(map[*yaml.Node]bool)
+		for i, item := range src.Content {
+			match := namedItem(dst.Content, item)
+			if match == nil && i < len(dst.Content) && itemName(dst.Content[i]) == "" {
+				match = dst.Content[i]
+			}
+			if match == nil || used[match] {
+				content[i] = item
+				continue
+			}
+			used[match] = true
+			mergeNodes(match, item)
+			content[i] = match
+		}
+		dst.Content = content
+	}
+}
+
+func itemName(node *yaml.Node) string {
+	if node.Kind != yaml.MappingNode {
+		return ""
+	}
+	if name := mappingValue(node, "name"); name != nil {
+		return name.Value
+	}
+	return ""
+}
+
+func namedItem(items []*yaml.Node, item *yaml.Node) *yaml.Node {
+	name := itemName(item)
+	if name == "" {
+		return nil
+	}
+	for _, candidate := range items {
+		if itemName(candidate) == name {
+			return candidate
+		}
+	}
+	return nil
+}
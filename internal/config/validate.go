@@ -0,0 +1,396 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Issue is a problem found in a config file. Issues don't stop muxyard from
+// starting; the offending setting is ignored or left as written.
+type Issue struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (i Issue) String() string {
+	if i.Line == 0 {
+		return fmt.Sprintf("%s: %s", i.File, i.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", i.File, i.Line, i.Message)
+}
+
+// decode reads one config file, upgrading it to the current version in
+// memory. Unknown fields and type errors are reported as issues while the
+// rest of the file is still decoded; a file that isn't valid YAML at all is
+// skipped and returns a nil config. Only a file too new to understand is
+// an error.
+func decode(path string, data []byte) (*Config, *yaml.Node, []Issue, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		issue := yamlIssue(path, strings.TrimPrefix(err.Error(), "yaml: "))
+		issue.Message += "; ignoring this file"
+		return nil, nil, []Issue{issue}, nil
+	}
+
+	var cfg Config
+	if len(root.Content) == 0 {
+		return &cfg, &root, nil, nil
+	}
+	if _, err := upgrade(&root); err != nil {
+		return nil, nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	issues := decodeNode(path, &root, &cfg)
+	return &cfg, &root, issues, nil
+}
+
+// decodeNode decodes a document into v, reporting unknown fields and type
+// errors as issues while the rest of the document is still decoded.
+func decodeNode(path string, root *yaml.Node, v any) []Issue {
+	issues := checkFields(path, root, reflect.TypeOf(v))
+
+	var typeErr *yaml.TypeError
+	if err := root.Decode(v); errors.As(err, &typeErr) {
+		for _, msg := range typeErr.Errors {
+			issues = append(issues, yamlIssue(path, msg))
+		}
+	} else if err != nil {
+		issues = append(issues, Issue{File: path, Message: err.Error()})
+	}
+	return issues
+}
+
+var yamlLinePattern = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// yamlIssue turns a yaml.v3 error message of the form "line N: ..." into an
+// issue.
+func yamlIssue(path, msg string) Issue {
+	issue := Issue{File: path, Message: msg}
+	if m := yamlLinePattern.FindStringSubmatch(msg); m != nil {
+		issue.Line, _ = strconv.Atoi(m[1])
+		issue.Message = m[2]
+	}
+	return issue
+}
+
+// sectionNames name the config types the way users know them from the file.
+var sectionNames = map[string]string{
+	"Config":          "the config",
+	"SessionTemplate": "template",
+	"TemplateParam":   "param",
+	"WindowConfig":    "window",
+	"PaneConfig":      "pane",
+	"ColorConfig":     "colors",
+	"ColorPair":       "color",
+	"TmuxConfig":      "tmux",
+	"ServerConfig":    "server",
+	"KeysConfig":      "keys",
+	"WorktreeConfig":  "worktrees",
+	"RepoDirectory":   "repo_directories entry",
+	"ZoxideConfig":    "zoxide",
+}
+
+var unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// checkFields reports mapping keys that match no field of t, suggesting the
+// field that was likely meant. It works on the node tree rather than the
+// file so that it sees documents after migration, with their original lines.
+func checkFields(path string, node *yaml.Node, t reflect.Type) []Issue {
+	for node.Kind == yaml.DocumentNode || node.Kind == yaml.AliasNode {
+		if node.Kind == yaml.AliasNode {
+			node = node.Alias
+		} else if len(node.Content) > 0 {
+			node = node.Content[0]
+		} else {
+			return nil
+		}
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// Types that decode themselves accept whatever shapes they like, but
+	// structs written out in full still have their fields checked
+	if reflect.PointerTo(t).Implements(unmarshalerType) && (t.Kind() != reflect.Struct || node.Kind != yaml.MappingNode) {
+		return nil
+	}
+
+	var issues []Issue
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			field, ok := fields[key.Value]
+			if !ok {
+				message := fmt.Sprintf("unknown field %q in %s", key.Value, sectionNames[t.Name()])
+				if suggestion := closestField(fields, key.Value); suggestion != "" {
+					message += fmt.Sprintf(" (did you mean %q?)", suggestion)
+				}
+				issues = append(issues, Issue{File: path, Line: key.Line, Message: message})
+				continue
+			}
+			issues = append(issues, checkFields(path, value, field)...)
+		}
+
+	case reflect.Slice:
+		if node.Kind == yaml.SequenceNode {
+			for _, item := range node.Content {
+				issues = append(issues, checkFields(path, item, t.Elem())...)
+			}
+		}
+
+	case reflect.Map:
+		if node.Kind == yaml.MappingNode {
+			for i := 1; i < len(node.Content); i += 2 {
+				issues = append(issues, checkFields(path, node.Content[i], t.Elem())...)
+			}
+		}
+	}
+	return issues
+}
+
+// yamlFields maps the yaml keys of a struct to the types of their fields.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// closestField returns the field nearest to name, if any is close enough
+// to be a typo.
+func closestField(fields map[string]reflect.Type, name string) string {
+	best, bestDistance := "", 3
+	for field := range fields {
+		if d := editDistance(name, field); d < bestDistance || d == bestDistance && field < best {
+			best, bestDistance = field, d
+		}
+	}
+	if bestDistance == 3 {
+		return ""
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// checkFile checks a single config file for problems strict decoding can't
+// catch: unknown themes, missing and duplicate template names, and bad
+// scan rules.
+func (c *Config) checkFile(path string, root *yaml.Node) []Issue {
+	var issues []Issue
+	add := func(line int, format string, args ...any) {
+		issues = append(issues, Issue{File: path, Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if c.Theme != "" && !slices.Contains(Themes, c.Theme) {
+		add(lineOf(root, "theme"), "unknown theme %q (available: %s)", c.Theme, strings.Join(Themes, ", "))
+	}
+
+	for i, dir := range c.RepoDirectories {
+		if dir.MaxDepth < 0 {
+			add(lineOf(root, "repo_directories", i, "max_depth"), "max_depth must not be negative, got %d", dir.MaxDepth)
+		}
+		checkGlobs := func(field string, globs []string) {
+			for j, glob := range globs {
+				if _, err := filepath.Match(glob, ""); err != nil {
+					add(lineOf(root, "repo_directories", i, field, j), "invalid %s glob %q", field, glob)
+				}
+			}
+		}
+		checkGlobs("exclude", dir.Exclude)
+		checkGlobs("include", dir.Include)
+	}
+
+	if c.Zoxide.MinScore < 0 {
+		add(lineOf(root, "zoxide", "min_score"), "min_score must not be negative, got %v", c.Zoxide.MinScore)
+	}
+
+	seen := make(map[string]int)
+	for i, template := range c.Templates {
+		line := lineOf(root, "templates", i, "name")
+		if template.Name == "" {
+			add(line, "template %d has no name", i+1)
+			continue
+		}
+		if first, ok := seen[template.Name]; ok {
+			add(line, "duplicate template name %q (first defined on line %d)", template.Name, first)
+			continue
+		}
+		seen[template.Name] = line
+	}
+
+	return issues
+}
+
+// templateOrigin records where a merged template was defined, so problems
+// found after merging can still be reported at the right line. path leads
+// from the document root to the template: an entry of templates in a
+// config file, the root itself in a project file.
+type templateOrigin struct {
+	file string
+	root *yaml.Node
+	path []any
+}
+
+func (o templateOrigin) issue(format string, args ...any) Issue {
+	return o.issueAt(nil, format, args...)
+}
+
+func (o templateOrigin) issueAt(path []any, format string, args ...any) Issue {
+	path = append(slices.Clone(o.path), path...)
+	return Issue{File: o.file, Line: lineOf(o.root, path...), Message: fmt.Sprintf(format, args...)}
+}
+
+// windowIssue reports a problem with a window of the resolved template at
+// the window's definition when the template defines it itself, or else at
+// the template, which got it from the template it extends or a fragment.
+func (o templateOrigin) windowIssue(window string, path []any, format string, args ...any) Issue {
+	node := o.root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	for _, step := range o.path {
+		switch step := step.(type) {
+		case string:
+			node = mappingValue(node, step)
+		case int:
+			if node.Kind == yaml.SequenceNode && step < len(node.Content) {
+				node = node.Content[step]
+			} else {
+				node = nil
+			}
+		}
+		if node == nil {
+			return o.issue(format, args...)
+		}
+	}
+	if windows := mappingValue(node, "windows"); windows != nil {
+		for i, item := range windows.Content {
+			if itemName(item) == window {
+				return o.issueAt(append([]any{"windows", i}, path...), format, args...)
+			}
+		}
+	}
+	return o.issue(format, args...)
+}
+
+// checkTemplates resolves the merged templates and checks the result; see
+// checkTemplate.
+func (c *Config) checkTemplates(origins []templateOrigin) []Issue {
+	var issues []Issue
+	errs := c.resolveTemplates()
+	for i, template := range c.Templates {
+		if errs[i] != nil {
+			issues = append(issues, origins[i].issue("%v", errs[i]))
+			continue
+		}
+		issues = append(issues, checkTemplate(template, origins[i])...)
+	}
+	return issues
+}
+
+// checkTemplate checks a resolved template for templates without windows,
+// unknown split directions, and focus on a window that doesn't exist.
+func checkTemplate(template SessionTemplate, origin templateOrigin) []Issue {
+	if len(template.Windows) == 0 {
+		return []Issue{origin.issueAt([]any{"windows"}, "template %q has no windows", template.Name)}
+	}
+
+	var issues []Issue
+	// Panes are checked once resolved, so that those inherited or included
+	// from fragments are too
+	for _, window := range template.Windows {
+		for p, pane := range window.Panes {
+			if _, err := pane.SplitHorizontal(); err != nil {
+				issues = append(issues, origin.windowIssue(window.Name, []any{"panes", p, "split"},
+					"template %q: window %q pane %d: split must be horizontal, vertical, h, or v, got %q",
+					template.Name, window.Name, p+1, pane.Split))
+			}
+		}
+	}
+	if template.FocusedWindow != "" && !strings.Contains(template.FocusedWindow, "{{") &&
+		windowIndex(template.Windows, template.FocusedWindow) < 0 {
+		issues = append(issues, origin.issueAt([]any{"focused_window"},
+			"focused_window %q names no window of template %q", template.FocusedWindow, template.Name))
+	}
+	return issues
+}
+
+// lineOf follows a path of mapping keys and sequence indexes from the
+// document root and returns the line of the deepest node found, so a
+// missing key is reported at its parent.
+func lineOf(root *yaml.Node, path ...any) int {
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	line := node.Line
+
+	for _, step := range path {
+		var next *yaml.Node
+		switch step := step.(type) {
+		case string:
+			if node.Kind != yaml.MappingNode {
+				return line
+			}
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == step {
+					// Report keys on their own line, not their value's
+					line = node.Content[i].Line
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case int:
+			if node.Kind == yaml.SequenceNode && step < len(node.Content) {
+				next = node.Content[step]
+				line = next.Line
+			}
+		}
+		if next == nil {
+			return line
+		}
+		node = next
+	}
+	return line
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Watcher notices changes to the config files by comparing their sizes and
+// modification times, which also catches editors that save by replacing
+// the file. Files appearing or disappearing count as changes too.
+type Watcher struct {
+	state string
+}
+
+// NewWatcher returns a watcher that reports changes made from now on.
+func NewWatcher() *Watcher {
+	return &Watcher{state: filesState()}
+}
+
+// Changed reports whether the config files changed since the last call.
+func (w *Watcher) Changed() bool {
+	state := filesState()
+	if state == w.state {
+		return false
+	}
+	w.state = state
+	return true
+}
+
+func filesState() string {
+	files, err := Files()
+	if err != nil {
+		return err.Error()
+	}
+
+	var b strings.Builder
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			fmt.Fprintf(&b, "%s: %v\n", file, err)
+			continue
+		}
+		fmt.Fprintf(&b, "%s %d %d\n", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String()
+}
+
+// Reload reads the config files again, like Load, but never writes the
+// default config; with no files left it returns the defaults.
+func Reload() (*Config, error) {
+	files, err := Files()
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return DefaultConfig(), nil
+	}
+	return LoadFiles(files...)
+}
@@ -0,0 +1,165 @@
+package frecency
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"muxyard/internal/fsutil"
+)
+
+// Version is the store format written by this build. Files with a newer
+// version are rejected rather than misread.
+const Version = 1
+
+// maxRank bounds the ranks of all entries of a kind together. Once they add
+// up to more, every rank is scaled down and the ones that drop below 1 are
+// forgotten, so that old habits fade.
+const maxRank = 1000
+
+// Store records how often and how recently sessions and directories were
+// used: sessions by server and name, as server:name, directories by path.
+type Store struct {
+	Version  int              `json:"version"`
+	Sessions map[string]Entry `json:"sessions,omitempty"`
+	Paths    map[string]Entry `json:"paths,omitempty"`
+}
+
+type Entry struct {
+	// Rank counts the visits, less what aging took off.
+	Rank     float64   `json:"rank"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// Score weighs the entry's rank by how recently it was used.
+func (e Entry) Score(now time.Time) float64 {
+	switch d := now.Sub(e.LastUsed); {
+	case d < time.Hour:
+		return e.Rank * 4
+	case d < 24*time.Hour:
+		return e.Rank * 2
+	case d < 7*24*time.Hour:
+		return e.Rank / 2
+	default:
+		return e.Rank / 4
+	}
+}
+
+// Path returns the store file under the XDG state directory.
+func Path() (string, error) {
+	stateHome := os.Getenv("XDG_STATE_HOME")
+	if stateHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		stateHome = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(stateHome, "muxyard", "frecency.json"), nil
+}
+
+// Load reads a store file. A missing file is an empty store.
+func Load(path string) (*Store, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Store{Version: Version}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var store Store
+	if err := json.Unmarshal(data, &store); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if store.Version > Version {
+		return nil, fmt.Errorf("%s has version %d, but this muxyard only understands up to %d; please upgrade", path, store.Version, Version)
+	}
+
+	return &store, nil
+}
+
+// Save writes a store file, replacing the previous one atomically so a
+// crash mid-write can't lose the history.
+func Save(path string, store *Store) error {
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return fsutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Visit records a use of a session on a tmux server and the directory it
+// was started in. Either can be empty, e.g. when attaching to a running
+// session whose directory isn't known.
+func (s *Store) Visit(server, session, path string, now time.Time) {
+	if session != "" {
+		if s.Sessions == nil {
+			s.Sessions = make(map[string]Entry)
+		}
+		visit(s.Sessions, sessionKey(server, session), now)
+	}
+	if path != "" {
+		if s.Paths == nil {
+			s.Paths = make(map[string]Entry)
+		}
+		visit(s.Paths, path, now)
+	}
+}
+
+func visit(entries map[string]Entry, key string, now time.Time) {
+	entry := entries[key]
+	entry.Rank++
+	entry.LastUsed = now
+	entries[key] = entry
+
+	var total float64
+	for _, entry := range entries {
+		total += entry.Rank
+	}
+	if total <= maxRank {
+		return
+	}
+	for key, entry := range entries {
+		entry.Rank *= 0.9
+		if entry.Rank < 1 {
+			delete(entries, key)
+			continue
+		}
+		entries[key] = entry
+	}
+}
+
+// SessionScore returns the frecency of a session on a tmux server, 0 for
+// one never used.
+func (s *Store) SessionScore(server, name string, now time.Time) float64 {
+	return s.Sessions[sessionKey(server, name)].Score(now)
+}
+
+// sessionKey tells sessions of the same name on different servers apart.
+// tmux doesn't allow colons in session names.
+func sessionKey(server, name string) string {
+	return server + ":" + name
+}
+
+// PathScore returns the frecency of a directory, 0 for one never used.
+func (s *Store) PathScore(path string, now time.Time) float64 {
+	return s.Paths[path].Score(now)
+}
+
+// Record adds a visit to the store file.
+func Record(server, session, path string) error {
+	file, err := Path()
+	if err != nil {
+		return err
+	}
+	store, err := Load(file)
+	if err != nil {
+		return err
+	}
+	store.Visit(server, session, path, time.Now())
+	return Save(file, store)
+}
@@ -0,0 +1,91 @@
+package frecency
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestVisitAndScore(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := &Store{Version: Version}
+
+	for i := 0; i < 3; i++ {
+		store.Visit("default", "api", "/src/api", now.Add(-10*24*time.Hour))
+	}
+	store.Visit("default", "web", "", now.Add(-time.Minute))
+
+	// Three old visits weigh less than a recent one
+	if api, web := store.SessionScore("default", "api", now), store.SessionScore("default", "web", now); api != 0.75 || web != 4 {
+		t.Errorf("Expected scores 0.75 and 4, got %v and %v", api, web)
+	}
+	if score := store.PathScore("/src/api", now); score != 0.75 {
+		t.Errorf("Expected the directory to be scored like its session, got %v", score)
+	}
+	if _, ok := store.Paths[""]; ok {
+		t.Error("Expected no entry for an unknown directory")
+	}
+	if score := store.SessionScore("default", "docs", now); score != 0 {
+		t.Errorf("Expected no score for an unused session, got %v", score)
+	}
+	if score := store.SessionScore("work", "web", now); score != 0 {
+		t.Errorf("Expected sessions on other servers to be scored apart, got %v", score)
+	}
+}
+
+func TestAging(t *testing.T) {
+	now := time.Now()
+	store := &Store{Version: Version}
+	store.Visit("default", "once", "", now)
+	for i := 0; i < maxRank; i++ {
+		store.Visit("default", "daily", "", now)
+	}
+
+	if _, ok := store.Sessions["default:once"]; ok {
+		t.Error("Expected a session used once to be forgotten as others pile up")
+	}
+	if rank := store.Sessions["default:daily"].Rank; rank > maxRank || rank < maxRank*0.8 {
+		t.Errorf("Expected the rank to be scaled down below %d, got %v", maxRank, rank)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "muxyard", "frecency.json")
+
+	store, err := Load(path)
+	if err != nil || len(store.Sessions) != 0 {
+		t.Fatalf("Expected an empty store for a missing file, got %+v (err %v)", store, err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	store.Visit("default", "api", "/src/api", now)
+	if err := Save(path, store); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry := loaded.Paths["/src/api"]; entry.Rank != 1 || !entry.LastUsed.Equal(now) {
+		t.Errorf("Expected the visit to be saved, got %+v", entry)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	for i := 0; i < 2; i++ {
+		if err := Record("default", "api", "/src/api"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path, _ := Path()
+	store, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rank := store.Sessions["default:api"].Rank; rank != 2 {
+		t.Errorf("Expected two recorded visits, got rank %v", rank)
+	}
+}
@@ -0,0 +1,36 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state", "store.json")
+
+	var wg sync.WaitGroup
+	for _, content := range []string{"first\n", "second\n"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := WriteFile(path, []byte(content), 0644); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(path)
+	if err != nil || (string(data) != "first\n" && string(data) != "second\n") {
+		t.Errorf("Expected one whole write to win, got %q (err %v)", data, err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0644 {
+		t.Errorf("Expected mode 0644, got %v", info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("Expected no temporary files left behind, got %v", entries)
+	}
+}
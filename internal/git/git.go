@@ -1,92 +1,456 @@
 package git
 
 import (
+	"fmt"
+	"io/fs"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"muxyard/internal/config"
 )
 
+// RepoKind says how a repository is laid out on disk.
+type RepoKind int
+
+const (
+	// Normal is a working tree with its own .git directory.
+	Normal RepoKind = iota
+	// Worktree is a linked worktree, whose .git file points into the
+	// repository it was added to.
+	Worktree
+	// Bare is a repository without a working tree.
+	Bare
+)
+
+func (k RepoKind) String() string {
+	switch k {
+	case Worktree:
+		return "worktree"
+	case Bare:
+		return "bare"
+	default:
+		return "repo"
+	}
+}
+
 type Repository struct {
-	Name string
-	Path string
+	Name string   `json:"name"`
+	Path string   `json:"path"`
+	Kind RepoKind `json:"kind,omitempty"`
+	// Branch is the checked out branch, or the abbreviated commit when
+	// HEAD is detached.
+	Branch string `json:"branch,omitempty"`
+	// MainPath is the repository a worktree belongs to: the working tree
+	// of a normal repository, or the directory of a bare one.
+	MainPath string `json:"main_path,omitempty"`
+	// Marker is the project marker the repository was found by, such as
+	// ".git" or "go.mod".
+	Marker string `json:"marker,omitempty"`
+	// Score is the zoxide score of the directory and the ones inside it,
+	// when zoxide is a project source.
+	Score float64 `json:"score,omitempty"`
 }
 
+// GitMarker is the project marker of git repositories, including
+// worktrees and bare repositories.
+const GitMarker = ".git"
+
 func (r Repository) String() string {
 	return r.Name
 }
 
-func FindRepositories(directories []string) ([]Repository, error) {
-	var repos []Repository
-	seen := make(map[string]bool)
+// IsGit reports whether the repository is a git repository. Repositories
+// that weren't found by their marker are taken to be.
+func (r Repository) IsGit() bool {
+	return r.Marker == "" || r.Marker == GitMarker
+}
 
+// SessionName is the name a session for the repository is based on: its
+// name, or for a worktree the name of its main repository and its branch,
+// as in repo@branch, so that sessions for worktrees don't clash.
+func (r Repository) SessionName() string {
+	if r.Kind != Worktree || r.Branch == "" {
+		return r.Name
+	}
+	return repoName(r.MainPath) + "@" + r.Branch
+}
+
+// repoName names a repository after its directory, without the .git suffix
+// bare repositories usually have.
+func repoName(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), ".git")
+	if name == "" {
+		return filepath.Base(path)
+	}
+	return name
+}
+
+// FindRepositories searches the directories for projects, following the
+// rules of each, several subdirectories at a time, and lists them by name.
+// A project is a directory with one of the markers. Within a project, only
+// version controlled ones are looked for, so that the packages of a
+// repository aren't listed as projects of their own.
+func FindRepositories(directories []config.RepoDirectory, markers []string) ([]Repository, error) {
+	s := &scanner{
+		markers:  markers,
+		slots:    make(chan struct{}, scanWorkers),
+		seen:     make(map[string]bool),
+		followed: make(map[string]bool),
+	}
 	for _, dir := range directories {
-		if dir == "" {
+		if dir.Path == "" {
 			continue
 		}
 
-		expandedDir := expandHome(dir)
-		if _, err := os.Stat(expandedDir); os.IsNotExist(err) {
+		root := filepath.Clean(expandHome(dir.Path))
+		if _, err := os.Stat(root); os.IsNotExist(err) {
 			continue
 		}
+		s.walk(&search{RepoDirectory: dir, root: root}, root, false)
+	}
+	s.wg.Wait()
+
+	return SortRepositories(s.repos), nil
+}
+
+// SortRepositories orders repositories by name, with worktrees right after
+// their main repository.
+func SortRepositories(repos []Repository) []Repository {
+	sort.Slice(repos, func(i, j int) bool {
+		if repos[i].Name != repos[j].Name {
+			return repos[i].Name < repos[j].Name
+		}
+		return repos[i].Path < repos[j].Path
+	})
+	return groupWorktrees(repos)
+}
 
-		dirRepos, err := findReposInDirectory(expandedDir)
-		if err != nil {
-			continue
+// groupWorktrees moves worktrees right after their main repository, when
+// that was found too.
+func groupWorktrees(repos []Repository) []Repository {
+	mains := make(map[string]bool)
+	for _, repo := range repos {
+		if repo.Kind != Worktree {
+			mains[repo.Path] = true
 		}
+	}
 
-		for _, repo := range dirRepos {
-			if !seen[repo.Path] {
-				repos = append(repos, repo)
-				seen[repo.Path] = true
+	grouped := make([]Repository, 0, len(repos))
+	for _, repo := range repos {
+		if repo.Kind == Worktree {
+			if !mains[repo.MainPath] {
+				grouped = append(grouped, repo)
+			}
+			continue
+		}
+		grouped = append(grouped, repo)
+		for _, worktree := range repos {
+			if worktree.Kind == Worktree && worktree.MainPath == repo.Path {
+				grouped = append(grouped, worktree)
 			}
 		}
 	}
+	return grouped
+}
 
-	sort.Slice(repos, func(i, j int) bool {
-		return repos[i].Name < repos[j].Name
-	})
+// scanWorkers bounds the directories walked at once.
+var scanWorkers = max(runtime.NumCPU(), 4)
 
-	return repos, nil
+// search is a repository directory being searched, with its path expanded.
+type search struct {
+	config.RepoDirectory
+	root string
 }
 
-func findReposInDirectory(dir string) ([]Repository, error) {
-	var repos []Repository
+// depth is how many levels below the root path is.
+func (s *search) depth(path string) int {
+	rel, err := filepath.Rel(s.root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
 
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
+// matches reports whether any of the globs matches path. Globs with a
+// slash match the path relative to the root, others the last element.
+func (s *search) matches(globs []string, path string) bool {
+	rel, err := filepath.Rel(s.root, path)
+	if err != nil {
+		return false
+	}
+	for _, glob := range globs {
+		name := filepath.Base(path)
+		if strings.Contains(glob, "/") {
+			name = rel
+		}
+		if ok, _ := filepath.Match(glob, name); ok {
+			return true
 		}
+	}
+	return false
+}
 
-		if !info.IsDir() {
-			return nil
+// scanner walks directory trees concurrently. Every directory above the
+// deepest level searched is walked by a job of its own, so that one large
+// directory doesn't hold up the rest.
+type scanner struct {
+	markers []string
+	slots   chan struct{}
+	wg      sync.WaitGroup
+
+	mu    sync.Mutex
+	seen  map[string]bool
+	repos []Repository
+	// followed are the targets of the symbolic links followed, so that
+	// links forming a loop are only followed once.
+	followed map[string]bool
+}
+
+// walk starts a job looking for projects in dir, which is part of the
+// search. inProject says whether dir is inside a project.
+func (s *scanner) walk(search *search, dir string, inProject bool) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		// A slot is only held while walking, never while waiting on the
+		// jobs started from here, so the jobs can't starve each other
+		s.slots <- struct{}{}
+		defer func() { <-s.slots }()
+
+		// WalkDir doesn't descend into a symbolic link it starts at, unless
+		// the link is written as a directory
+		start := dir
+		if info, err := os.Lstat(dir); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+			start = dir + string(filepath.Separator)
 		}
 
-		if info.Name() == ".git" {
-			repoPath := filepath.Dir(path)
-			repoName := filepath.Base(repoPath)
+		// Only dir itself can be a project that the directories walked here
+		// are inside of; its subdirectories get jobs of their own
+		below := inProject
+		filepath.WalkDir(start, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+
+			if path == start {
+				path = dir
+			} else {
+				if d.Type()&fs.ModeSymlink != 0 && search.FollowSymlinks {
+					if search.depth(path) <= search.Depth() && !search.matches(search.Excluded(), path) && s.follow(path) {
+						s.walk(search, path, below)
+					}
+					return nil
+				}
+				if !d.IsDir() {
+					return nil
+				}
 
-			repos = append(repos, Repository{
-				Name: repoName,
-				Path: repoPath,
-			})
+				if search.matches(search.Excluded(), path) {
+					return filepath.SkipDir
+				}
+				depth := search.depth(path)
+				if depth > search.Depth() {
+					return filepath.SkipDir
+				}
+				if depth < search.Depth() {
+					s.walk(search, path, below)
+					return filepath.SkipDir
+				}
+			}
 
-			return filepath.SkipDir
+			inside := below
+			if path == dir {
+				inside = inProject
+			}
+			if repo, ok := s.detect(path, inside); ok {
+				if path == dir {
+					below = true
+				}
+				if len(search.Include) == 0 || search.matches(search.Include, path) {
+					s.add(repo, search.FollowSymlinks)
+				}
+				// Working trees can hold further repositories; bare ones can't
+				if repo.Kind == Bare {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		})
+	}()
+}
+
+// detect reports whether path is a project, by the first of the markers
+// found in it. Inside another project only version controlled directories
+// count.
+func (s *scanner) detect(path string, inProject bool) (Repository, bool) {
+	for _, marker := range s.markers {
+		if marker == GitMarker {
+			// Bare repositories and worktrees have no .git directory
+			if repo, ok := RepositoryAt(path); ok {
+				repo.Marker = marker
+				return repo, true
+			}
+			continue
+		}
+		if inProject && !vcsMarkers[marker] {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(path, marker)); err == nil {
+			return Repository{Name: filepath.Base(path), Path: path, Marker: marker}, true
 		}
+	}
+	return Repository{}, false
+}
+
+// vcsMarkers are the markers of version controlled directories other than
+// git repositories, which are found inside other projects too.
+var vcsMarkers = map[string]bool{".hg": true, ".jj": true, ".svn": true}
+
+// follow reports whether the symbolic link at path leads to a directory
+// that no other followed link led to.
+func (s *scanner) follow(path string) bool {
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return false
+	}
+	if info, err := os.Stat(target); err != nil || !info.IsDir() {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.followed[target] {
+		return false
+	}
+	s.followed[target] = true
+	return true
+}
 
-		if strings.HasPrefix(info.Name(), ".") && info.Name() != ".git" {
-			return filepath.SkipDir
+// add records a repository, once even when directories overlap. With
+// symbolic links followed, a repository can also be reached by several
+// paths, so the path the links lead to is what counts.
+func (s *scanner) add(repo Repository, resolve bool) {
+	key := repo.Path
+	if resolve {
+		if target, err := filepath.EvalSymlinks(repo.Path); err == nil {
+			key = target
 		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.seen[key] {
+		s.seen[key] = true
+		s.repos = append(s.repos, repo)
+	}
+}
 
-		depth := strings.Count(strings.TrimPrefix(path, dir), string(filepath.Separator))
-		if depth > 3 {
-			return filepath.SkipDir
+// RepositoryAt reports whether path is a repository: a working tree with a
+// .git directory, a worktree or submodule with a .git file, or a bare
+// repository.
+func RepositoryAt(path string) (Repository, bool) {
+	repo := Repository{Name: filepath.Base(path), Path: path}
+
+	dotGit := filepath.Join(path, ".git")
+	info, err := os.Stat(dotGit)
+	switch {
+	case err == nil && info.IsDir():
+		repo.Branch = headBranch(dotGit)
+		return repo, true
+
+	case err == nil:
+		gitDir, err := readGitFile(dotGit)
+		if err != nil {
+			return repo, false
 		}
+		repo.Branch = headBranch(gitDir)
+		// Worktrees share the common directory of their main repository;
+		// submodules have a git directory of their own
+		if common := commonDir(gitDir); common != gitDir {
+			repo.Kind = Worktree
+			repo.MainPath = common
+			if filepath.Base(common) == ".git" {
+				repo.MainPath = filepath.Dir(common)
+			}
+		}
+		return repo, true
 
-		return nil
-	})
+	case isBare(path):
+		repo.Kind = Bare
+		repo.Name = repoName(path)
+		repo.Branch = headBranch(path)
+		return repo, true
+	}
+
+	return repo, false
+}
+
+// readGitFile returns the git directory a .git file points to.
+func readGitFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return "", fmt.Errorf("%s: not a gitdir file", path)
+	}
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(path), gitDir)
+	}
+	return filepath.Clean(gitDir), nil
+}
 
-	return repos, err
+// commonDir returns the directory a git directory shares its objects and
+// refs with, which is the git directory itself unless it is a worktree's.
+func commonDir(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	common := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(common) {
+		common = filepath.Join(gitDir, common)
+	}
+	return filepath.Clean(common)
+}
+
+// headBranch reads the branch HEAD points to from a git directory, without
+// running git.
+func headBranch(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+	head := strings.TrimSpace(string(data))
+	if ref, ok := strings.CutPrefix(head, "ref: "); ok {
+		return strings.TrimPrefix(ref, "refs/heads/")
+	}
+	if len(head) > 7 {
+		return head[:7]
+	}
+	return head
+}
+
+func isBare(path string) bool {
+	head, err := os.Stat(filepath.Join(path, "HEAD"))
+	if err != nil || head.IsDir() {
+		return false
+	}
+	for _, dir := range []string{"objects", "refs"} {
+		if info, err := os.Stat(filepath.Join(path, dir)); err != nil || !info.IsDir() {
+			return false
+		}
+	}
+	return true
 }
 
 func expandHome(path string) string {
@@ -100,8 +464,139 @@ func expandHome(path string) string {
 	return path
 }
 
+// IsGitRepository reports whether path is the top of a working tree,
+// including linked worktrees.
 func IsGitRepository(path string) bool {
-	gitDir := filepath.Join(path, ".git")
-	info, err := os.Stat(gitDir)
-	return err == nil && info.IsDir()
+	_, err := os.Stat(filepath.Join(path, ".git"))
+	return err == nil
+}
+
+// CurrentBranch returns the branch checked out in the repository at path,
+// or the abbreviated commit when HEAD is detached.
+func CurrentBranch(path string) (string, error) {
+	output, err := exec.Command("git", "-C", path, "symbolic-ref", "--quiet", "--short", "HEAD").Output()
+	if err == nil {
+		return strings.TrimSpace(string(output)), nil
+	}
+
+	output, err = exec.Command("git", "-C", path, "rev-parse", "--short", "HEAD").Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// git runs a git command in dir and returns its trimmed output. Failures
+// carry git's own message.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// AddWorktree adds a worktree of the repository at repoPath in path with
+// branch checked out. An existing local branch is used as is, a branch
+// only on a remote is checked out tracking it, and any other name creates
+// a new branch from HEAD.
+func AddWorktree(repoPath, path, branch string) error {
+	// This also keeps a name starting with a dash from being taken as an
+	// option
+	if _, err := git(repoPath, "check-ref-format", "--branch", branch); err != nil {
+		return fmt.Errorf("%q is not a valid branch name", branch)
+	}
+	if _, err := git(repoPath, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err == nil {
+		_, err = git(repoPath, "worktree", "add", "--", path, branch)
+		return err
+	}
+	if remote, _ := git(repoPath, "for-each-ref", "--format=%(refname)", "refs/remotes/*/"+branch); remote != "" {
+		_, err := git(repoPath, "worktree", "add", "--", path, branch)
+		return err
+	}
+	_, err := git(repoPath, "worktree", "add", "-b", branch, "--", path)
+	return err
+}
+
+// IsDirty reports whether the working tree at path has uncommitted changes
+// or untracked files.
+func IsDirty(path string) (bool, error) {
+	status, err := git(path, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return status != "", nil
+}
+
+// RemoveWorktree removes a worktree, refusing when it is dirty. The branch
+// it had checked out is kept.
+func RemoveWorktree(repo Repository) error {
+	if repo.Kind != Worktree {
+		return fmt.Errorf("%s is not a worktree", repo.Path)
+	}
+	dirty, err := IsDirty(repo.Path)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("%s has uncommitted changes", repo.Path)
+	}
+	_, err = git(repo.MainPath, "worktree", "remove", repo.Path)
+	return err
+}
+
+// Status is what the repository list shows about a repository beyond its
+// name and path.
+type Status struct {
+	Branch string
+	// Dirty is set when the working tree has uncommitted changes or
+	// untracked files.
+	Dirty bool
+	// Upstream is set when the branch tracks one; Ahead and Behind count
+	// the commits between the two.
+	Upstream      bool
+	Ahead, Behind int
+	// LastCommit is the commit time of HEAD, zero before the first commit.
+	LastCommit time.Time
+}
+
+// RepoStatus reads the status of a repository. Bare repositories have no
+// working tree, so only their branch and last commit are filled in.
+func RepoStatus(repo Repository) (Status, error) {
+	status := Status{Branch: repo.Branch}
+
+	if repo.Kind != Bare {
+		output, err := git(repo.Path, "status", "--porcelain=v2", "--branch")
+		if err != nil {
+			return status, err
+		}
+		for _, line := range strings.Split(output, "\n") {
+			switch {
+			case strings.HasPrefix(line, "# branch.head "):
+				if head := strings.TrimPrefix(line, "# branch.head "); head != "(detached)" {
+					status.Branch = head
+				}
+			case strings.HasPrefix(line, "# branch.ab "):
+				status.Upstream = true
+				fmt.Sscanf(strings.TrimPrefix(line, "# branch.ab "), "+%d -%d", &status.Ahead, &status.Behind)
+			case line != "" && !strings.HasPrefix(line, "#"):
+				status.Dirty = true
+			}
+		}
+	}
+
+	// An unborn branch has no commit to show
+	if output, err := git(repo.Path, "log", "-1", "--format=%ct"); err == nil && output != "" {
+		if seconds, err := strconv.ParseInt(output, 10, 64); err == nil {
+			status.LastCommit = time.Unix(seconds, 0)
+		}
+	}
+
+	return status, nil
 }
@@ -2,8 +2,15 @@ package git
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
+	"time"
+
+	"muxyard/internal/config"
 )
 
 func TestIsGitRepository(t *testing.T) {
@@ -30,7 +37,7 @@ func TestFindRepositories(t *testing.T) {
 	}
 
 	projectParent := filepath.Dir(filepath.Join(wd, "..", ".."))
-	repos, err := FindRepositories([]string{projectParent})
+	repos, err := FindRepositories([]config.RepoDirectory{{Path: projectParent}}, config.DefaultProjectMarkers)
 	if err != nil {
 		t.Fatalf("FindRepositories failed: %v", err)
 	}
@@ -40,3 +47,315 @@ func TestFindRepositories(t *testing.T) {
 		t.Logf("  %s: %s", repo.Name, repo.Path)
 	}
 }
+
+func TestCurrentBranch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available on this system")
+	}
+
+	dir := t.TempDir()
+	cmd := exec.Command("git", "init", "--quiet", "--initial-branch", "trunk", dir)
+	if err := cmd.Run(); err != nil {
+		t.Skipf("git init failed: %v", err)
+	}
+
+	branch, err := CurrentBranch(dir)
+	if err != nil {
+		t.Fatalf("CurrentBranch failed: %v", err)
+	}
+	if branch != "trunk" {
+		t.Errorf("CurrentBranch() = %q, want trunk", branch)
+	}
+
+	if _, err := CurrentBranch(t.TempDir()); err == nil {
+		t.Error("Expected error outside a repository")
+	}
+}
+
+func TestFindWorktreesAndBareRepositories(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available on this system")
+	}
+
+	root := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Skipf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	api := filepath.Join(root, "api")
+	run("init", "--quiet", "--initial-branch", "main", api)
+	run("-C", api, "commit", "--quiet", "--allow-empty", "-m", "init")
+	run("-C", api, "worktree", "add", "--quiet", "-b", "login", filepath.Join(root, "zz-login"))
+	run("init", "--quiet", "--bare", "--initial-branch", "main", filepath.Join(root, "tools.git"))
+
+	repos, err := FindRepositories([]config.RepoDirectory{{Path: root}}, config.DefaultProjectMarkers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Repository{
+		{Name: "api", Path: api, Branch: "main", Marker: GitMarker},
+		{Name: "zz-login", Path: filepath.Join(root, "zz-login"), Kind: Worktree, Branch: "login", MainPath: api, Marker: GitMarker},
+		{Name: "tools", Path: filepath.Join(root, "tools.git"), Kind: Bare, Branch: "main", Marker: GitMarker},
+	}
+	if !reflect.DeepEqual(repos, want) {
+		t.Errorf("FindRepositories() =\n%+v\nwant\n%+v", repos, want)
+	}
+	if name := repos[1].SessionName(); name != "api@login" {
+		t.Errorf("SessionName() = %q, want api@login", name)
+	}
+}
+
+func TestAddWorktree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available on this system")
+	}
+
+	root := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Skipf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	api := filepath.Join(root, "api")
+	run("init", "--quiet", "--initial-branch", "main", api)
+	run("-C", api, "commit", "--quiet", "--allow-empty", "-m", "init")
+	run("-C", api, "branch", "existing")
+
+	for _, branch := range []string{"login", "existing"} {
+		path := filepath.Join(root, "api-"+branch)
+		if err := AddWorktree(api, path, branch); err != nil {
+			t.Fatalf("AddWorktree(%q) failed: %v", branch, err)
+		}
+		if repo, _ := RepositoryAt(path); repo.Kind != Worktree || repo.Branch != branch {
+			t.Errorf("Expected a worktree of %s, got %+v", branch, repo)
+		}
+	}
+
+	for _, branch := range []string{"-f", "--detach", "a..b", ""} {
+		path := filepath.Join(root, "api-bad")
+		if err := AddWorktree(api, path, branch); err == nil || !strings.Contains(err.Error(), "not a valid branch name") {
+			t.Errorf("AddWorktree(%q): expected an invalid branch name, got %v", branch, err)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("AddWorktree(%q): expected no worktree, got %v", branch, err)
+		}
+	}
+}
+
+func TestRepoStatus(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available on this system")
+	}
+
+	root := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t",
+			"GIT_COMMITTER_DATE=2024-05-01T12:00:00Z")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Skipf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	upstream := filepath.Join(root, "upstream")
+	clone := filepath.Join(root, "clone")
+	run("init", "--quiet", "--initial-branch", "main", upstream)
+	run("-C", upstream, "commit", "--quiet", "--allow-empty", "-m", "init")
+	run("clone", "--quiet", upstream, clone)
+	run("-C", clone, "commit", "--quiet", "--allow-empty", "-m", "local")
+
+	repo, _ := RepositoryAt(clone)
+	status, err := RepoStatus(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Status{Branch: "main", Upstream: true, Ahead: 1, LastCommit: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	if !status.LastCommit.Equal(want.LastCommit) {
+		t.Errorf("LastCommit = %v, want %v", status.LastCommit, want.LastCommit)
+	}
+	status.LastCommit = want.LastCommit
+	if status != want {
+		t.Errorf("RepoStatus() = %+v, want %+v", status, want)
+	}
+
+	if err := os.WriteFile(filepath.Join(clone, "notes.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if status, err := RepoStatus(repo); err != nil || !status.Dirty {
+		t.Errorf("Expected an untracked file to make the repository dirty, got %+v (err %v)", status, err)
+	}
+}
+
+func TestFindRepositoriesDepth(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{
+		"a",
+		"b/c",
+		"d/e/f",
+		"d/e/f/g",   // nested in a working tree
+		"h/i/j/k",   // too deep
+		".hidden/l", // hidden
+		"m/.n/o",    // hidden
+	} {
+		if err := os.MkdirAll(filepath.Join(root, dir, ".git"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repos, err := FindRepositories([]config.RepoDirectory{{Path: root}, {Path: root + "/b"}}, config.DefaultProjectMarkers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	for _, repo := range repos {
+		paths = append(paths, strings.TrimPrefix(repo.Path, root+"/"))
+	}
+	want := []string{"a", "b/c", "d/e/f"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("FindRepositories() found %q, want %q", paths, want)
+	}
+}
+
+func TestFindRepositoriesRules(t *testing.T) {
+	root := t.TempDir()
+	other := t.TempDir()
+	for _, dir := range []string{
+		"a/b/c/d/deep",
+		"web/node_modules/dep",
+		"acme/api",
+		"acme/.cache/tool",
+		"personal/notes",
+	} {
+		if err := os.MkdirAll(filepath.Join(root, dir, ".git"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(other, "linked", ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(other, filepath.Join(root, "elsewhere")); err != nil {
+		t.Fatal(err)
+	}
+	// A loop back to the root must not be followed forever
+	if err := os.Symlink(root, filepath.Join(other, "back")); err != nil {
+		t.Fatal(err)
+	}
+
+	find := func(dir config.RepoDirectory) []string {
+		t.Helper()
+		dir.Path = root
+		repos, err := FindRepositories([]config.RepoDirectory{dir}, config.DefaultProjectMarkers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var paths []string
+		for _, repo := range repos {
+			paths = append(paths, strings.TrimPrefix(repo.Path, root+"/"))
+		}
+		sort.Strings(paths)
+		return paths
+	}
+
+	tests := []struct {
+		name string
+		dir  config.RepoDirectory
+		want []string
+	}{
+		{"defaults", config.RepoDirectory{}, []string{"acme/api", "personal/notes"}},
+		{"deeper", config.RepoDirectory{MaxDepth: 5}, []string{"a/b/c/d/deep", "acme/api", "personal/notes"}},
+		{"no excludes", config.RepoDirectory{Exclude: []string{}}, []string{"acme/.cache/tool", "acme/api", "personal/notes", "web/node_modules/dep"}},
+		{"excluded path", config.RepoDirectory{Exclude: []string{".*", "node_modules", "personal/*"}}, []string{"acme/api"}},
+		{"included", config.RepoDirectory{Include: []string{"acme/*"}}, []string{"acme/api"}},
+		{"symlinks", config.RepoDirectory{FollowSymlinks: true}, []string{"acme/api", "elsewhere/linked", "personal/notes"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := find(tt.dir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindRepositories() found %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindProjectMarkers(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"both/.git", "hg/.hg", "mono/tools/.jj", "mono/web", "web", "plain/src"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"both/go.mod", "mono/go.mod", "mono/web/package.json", "web/package.json"} {
+		if err := os.WriteFile(filepath.Join(root, file), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	find := func(markers []string) []string {
+		t.Helper()
+		repos, err := FindRepositories([]config.RepoDirectory{{Path: root}}, markers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var found []string
+		for _, repo := range repos {
+			found = append(found, strings.TrimPrefix(repo.Path, root+"/")+" "+repo.Marker)
+		}
+		sort.Strings(found)
+		return found
+	}
+
+	// Packages inside a project aren't projects, but repositories are
+	want := []string{"both .git", "hg .hg", "mono go.mod", "mono/tools .jj", "web package.json"}
+	if got := find(config.DefaultProjectMarkers); !reflect.DeepEqual(got, want) {
+		t.Errorf("FindRepositories() found %q, want %q", got, want)
+	}
+
+	want = []string{"both go.mod", "mono go.mod", "web package.json"}
+	if got := find([]string{"go.mod", "package.json"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("FindRepositories() with custom markers found %q, want %q", got, want)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	path, err := IndexPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	directories := []config.RepoDirectory{{Path: "~/src"}, {Path: "~/work", MaxDepth: 5}}
+
+	if _, ok := LoadIndex(path, directories, config.DefaultProjectMarkers); ok {
+		t.Error("Expected no index before the first scan")
+	}
+
+	repos := []Repository{
+		{Name: "api", Path: "/src/api", Branch: "main"},
+		{Name: "api-login", Path: "/src/api-login", Kind: Worktree, Branch: "login", MainPath: "/src/api"},
+	}
+	if err := SaveIndex(path, directories, config.DefaultProjectMarkers, repos); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := LoadIndex(path, directories, config.DefaultProjectMarkers)
+	if !ok || !reflect.DeepEqual(got, repos) {
+		t.Errorf("LoadIndex() = %+v, %v, want %+v", got, ok, repos)
+	}
+	if _, ok := LoadIndex(path, directories[:1], config.DefaultProjectMarkers); ok {
+		t.Error("Expected the index to be ignored for other directories")
+	}
+	if _, ok := LoadIndex(path, []config.RepoDirectory{{Path: "~/src"}, {Path: "~/work"}}, config.DefaultProjectMarkers); ok {
+		t.Error("Expected the index to be ignored for other rules")
+	}
+	if _, ok := LoadIndex(path, directories, []string{".git"}); ok {
+		t.Error("Expected the index to be ignored for other markers")
+	}
+}
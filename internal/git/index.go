@@ -0,0 +1,79 @@
+package git
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"time"
+
+	"muxyard/internal/config"
+	"muxyard/internal/fsutil"
+)
+
+// IndexVersion is the index format written by this build. The index is only
+// a cache, so files of any other version are ignored rather than rejected.
+const IndexVersion = 3
+
+// Index is the result of the last scan of the repository directories, kept
+// so that the repository list can be shown before a new scan finishes.
+type Index struct {
+	Version      int                    `json:"version"`
+	ScannedAt    time.Time              `json:"scanned_at"`
+	Directories  []config.RepoDirectory `json:"directories"`
+	Markers      []string               `json:"markers"`
+	Repositories []Repository           `json:"repositories"`
+}
+
+// IndexPath returns the index file under the XDG cache directory.
+func IndexPath() (string, error) {
+	cacheHome := os.Getenv("XDG_CACHE_HOME")
+	if cacheHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		cacheHome = filepath.Join(home, ".cache")
+	}
+	return filepath.Join(cacheHome, "muxyard", "repos.json"), nil
+}
+
+// LoadIndex returns the repositories last found in the directories by the
+// markers. ok is false when there is no usable index for them: none was
+// written yet, it is unreadable, or it was written for other directories,
+// rules, or markers.
+func LoadIndex(path string, directories []config.RepoDirectory, markers []string) (repos []Repository, ok bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var index Index
+	if err := json.Unmarshal(data, &index); err != nil || index.Version != IndexVersion {
+		return nil, false
+	}
+	if !reflect.DeepEqual(index.Directories, directories) || !slices.Equal(index.Markers, markers) {
+		return nil, false
+	}
+	return index.Repositories, true
+}
+
+// SaveIndex writes the repositories found in the directories by the
+// markers, replacing the previous index atomically so that a crash
+// mid-write can't leave half of one behind.
+func SaveIndex(path string, directories []config.RepoDirectory, markers []string, repos []Repository) error {
+	index := Index{
+		Version:      IndexVersion,
+		ScannedAt:    time.Now(),
+		Directories:  directories,
+		Markers:      markers,
+		Repositories: repos,
+	}
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return fsutil.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,221 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"muxyard/internal/config"
+	"muxyard/internal/fsutil"
+	"muxyard/internal/tmux"
+)
+
+// Version is the state file format written by this build. Files with a
+// newer version are rejected rather than misread.
+const Version = 1
+
+type Snapshot struct {
+	Version  int       `json:"version"`
+	SavedAt  time.Time `json:"saved_at"`
+	Sessions []Session `json:"sessions"`
+}
+
+type Session struct {
+	Name    string   `json:"name"`
+	Windows []Window `json:"windows"`
+}
+
+type Window struct {
+	Name   string `json:"name"`
+	Layout string `json:"layout,omitempty"`
+	Active bool   `json:"active,omitempty"`
+	Panes  []Pane `json:"panes"`
+}
+
+type Pane struct {
+	Path    string `json:"path"`
+	Command string `json:"command,omitempty"`
+	Active  bool   `json:"active,omitempty"`
+}
+
+// shells are not recorded as pane commands; restoring them would only
+// start a second shell inside the first.
+var shells = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "fish": true, "dash": true,
+	"ksh": true, "tcsh": true, "csh": true, "nu": true,
+}
+
+// Capture records every session on the client's server. When withCommands
+// is set, the command running in each pane is recorded too.
+func Capture(client *tmux.Client, withCommands bool) (*Snapshot, error) {
+	sessions, err := client.ListSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &Snapshot{Version: Version, SavedAt: time.Now()}
+	for _, session := range sessions {
+		windows, err := client.ListWindows(session.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list windows of %s: %w", session.Name, err)
+		}
+		panes, err := client.ListPanes(session.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list panes of %s: %w", session.Name, err)
+		}
+
+		saved := Session{Name: session.Name}
+		for _, window := range windows {
+			savedWindow := Window{
+				Name:   window.Name,
+				Layout: window.Layout,
+				Active: window.Active,
+			}
+			for _, pane := range panes {
+				if pane.WindowIndex != window.Index {
+					continue
+				}
+				savedPane := Pane{Path: pane.Path, Active: pane.Active}
+				if withCommands && !shells[pane.Command] {
+					savedPane.Command = pane.Command
+				}
+				savedWindow.Panes = append(savedWindow.Panes, savedPane)
+			}
+			saved.Windows = append(saved.Windows, savedWindow)
+		}
+		snap.Sessions = append(snap.Sessions, saved)
+	}
+
+	return snap, nil
+}
+
+// Path returns the state file for a tmux server under the XDG data
+// directory. The default server keeps the plain sessions.json name.
+func Path(server string) (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+
+	name := "sessions.json"
+	if server != "" && server != "default" {
+		name = fmt.Sprintf("sessions-%s.json", server)
+	}
+	return filepath.Join(dataHome, "muxyard", name), nil
+}
+
+// Load reads a state file. A missing file is an empty snapshot.
+func Load(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Snapshot{Version: Version}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if snap.Version > Version {
+		return nil, fmt.Errorf("%s has version %d, but this muxyard only understands up to %d; please upgrade", path, snap.Version, Version)
+	}
+
+	return &snap, nil
+}
+
+// Save writes a state file, replacing the previous one atomically so a
+// crash mid-write can't lose the last good snapshot.
+func Save(path string, snap *Snapshot) error {
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return fsutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func (s *Snapshot) Find(name string) (*Session, bool) {
+	for i := range s.Sessions {
+		if s.Sessions[i].Name == name {
+			return &s.Sessions[i], true
+		}
+	}
+	return nil, false
+}
+
+// Missing returns the saved sessions that are not currently running.
+func (s *Snapshot) Missing(running []tmux.Session) []Session {
+	names := make(map[string]bool, len(running))
+	for _, session := range running {
+		names[session.Name] = true
+	}
+
+	var missing []Session
+	for _, session := range s.Sessions {
+		if !names[session.Name] {
+			missing = append(missing, session)
+		}
+	}
+	return missing
+}
+
+// Path returns the directory the session was started in, taken from its
+// first pane.
+func (s Session) Path() string {
+	for _, window := range s.Windows {
+		if len(window.Panes) > 0 {
+			return window.Panes[0].Path
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return home
+	}
+	return "/"
+}
+
+// Template describes the saved session as a session template, so restoring
+// goes through the same machinery as creating a session.
+func (s Session) Template() *config.SessionTemplate {
+	template := &config.SessionTemplate{Name: s.Name}
+	names := make(map[string]int)
+	for _, window := range s.Windows {
+		names[window.Name]++
+	}
+
+	for _, window := range s.Windows {
+		windowConfig := config.WindowConfig{Name: window.Name, Layout: window.Layout}
+		for _, pane := range window.Panes {
+			windowConfig.Panes = append(windowConfig.Panes, config.PaneConfig{
+				Command: pane.Command,
+				Path:    pane.Path,
+				Focus:   pane.Active,
+			})
+		}
+		if len(windowConfig.Panes) == 0 {
+			windowConfig.Panes = []config.PaneConfig{{}}
+		}
+		// Windows are focused by name, which only works if it's unique
+		if window.Active && window.Name != "" && names[window.Name] == 1 {
+			template.FocusedWindow = window.Name
+		}
+		template.Windows = append(template.Windows, windowConfig)
+	}
+
+	return template
+}
+
+// Restore recreates a saved session on the client's server.
+func Restore(client *tmux.Client, session Session) error {
+	if len(session.Windows) == 0 {
+		return fmt.Errorf("saved session %s has no windows", session.Name)
+	}
+	return client.CreateSession(session.Name, session.Path(), session.Template())
+}
@@ -0,0 +1,83 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"muxyard/internal/config"
+	"muxyard/internal/tmux"
+)
+
+func TestCaptureAndTemplate(t *testing.T) {
+	runner := tmux.NewFakeRunner()
+	runner.Respond("list-sessions", "api:2:1:1714564800:/src/api\n", nil)
+	runner.Respond("list-windows", "0\teditor\t1\t0\tc5a1,80x24,0,0,1\n1\tdev\t2\t1\t9a3d,80x24,0,0[80x12,0,0,2,80x11,0,13,3]\n", nil)
+	runner.Respond("list-panes", "0\t0\tnvim\t/src/api\t1\n1\t0\tzsh\t/src/api\t0\n1\t1\tgo\t/src/api/cmd\t1\n", nil)
+
+	snap, err := Capture(tmux.NewClient(runner), true)
+	if err != nil {
+		t.Fatalf("Capture failed: %v", err)
+	}
+	if len(snap.Sessions) != 1 {
+		t.Fatalf("Expected 1 session, got %d", len(snap.Sessions))
+	}
+
+	session := snap.Sessions[0]
+	if session.Path() != "/src/api" {
+		t.Errorf("Path() = %q, want /src/api", session.Path())
+	}
+
+	expected := &config.SessionTemplate{
+		Name:          "api",
+		FocusedWindow: "dev",
+		Windows: []config.WindowConfig{
+			{
+				Name:   "editor",
+				Layout: "c5a1,80x24,0,0,1",
+				Panes:  []config.PaneConfig{{Command: "nvim", Path: "/src/api", Focus: true}},
+			},
+			{
+				Name:   "dev",
+				Layout: "9a3d,80x24,0,0[80x12,0,0,2,80x11,0,13,3]",
+				Panes: []config.PaneConfig{
+					{Path: "/src/api"},
+					{Command: "go", Path: "/src/api/cmd", Focus: true},
+				},
+			},
+		},
+	}
+	if template := session.Template(); !reflect.DeepEqual(template, expected) {
+		t.Errorf("Template() = %+v, want %+v", template, expected)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "muxyard", "sessions.json")
+
+	missing, err := Load(path)
+	if err != nil || len(missing.Sessions) != 0 {
+		t.Fatalf("Expected an empty snapshot for a missing file, got %+v (err %v)", missing, err)
+	}
+
+	snap := &Snapshot{Version: Version, Sessions: []Session{{Name: "api"}}}
+	if err := Save(path, snap); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(loaded.Sessions) != 1 || loaded.Sessions[0].Name != "api" {
+		t.Errorf("Load() = %+v, want the saved session", loaded)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version": 99}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Expected error for a state file from a newer version")
+	}
+}
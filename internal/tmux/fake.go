@@ -0,0 +1,88 @@
+package tmux
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FakeRunner is a Runner that records every call instead of running tmux.
+// Responses are queued per tmux subcommand; the last queued response for a
+// subcommand is reused once the queue is drained. Without a queued response,
+// commands that print new window or pane IDs get fresh ones generated.
+type FakeRunner struct {
+	Calls     [][]string
+	responses map[string][]FakeResponse
+	nextID    int
+}
+
+type FakeResponse struct {
+	Output string
+	Err    error
+}
+
+// FakeExitError mimics a tmux process exiting with a non-zero status.
+type FakeExitError struct {
+	Code int
+}
+
+func (e *FakeExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+func (e *FakeExitError) ExitCode() int {
+	return e.Code
+}
+
+func NewFakeRunner() *FakeRunner {
+	return &FakeRunner{responses: make(map[string][]FakeResponse)}
+}
+
+// Respond queues a response for the next call of the given subcommand.
+func (f *FakeRunner) Respond(subcommand, output string, err error) {
+	f.responses[subcommand] = append(f.responses[subcommand], FakeResponse{Output: output, Err: err})
+}
+
+// Commands returns the recorded calls joined into single strings, which
+// keeps test expectations short.
+func (f *FakeRunner) Commands() []string {
+	commands := make([]string, len(f.Calls))
+	for i, call := range f.Calls {
+		commands[i] = strings.Join(call, " ")
+	}
+	return commands
+}
+
+func (f *FakeRunner) Run(args ...string) error {
+	_, err := f.Output(args...)
+	return err
+}
+
+func (f *FakeRunner) Output(args ...string) (string, error) {
+	f.Calls = append(f.Calls, append([]string(nil), args...))
+	if len(args) == 0 {
+		return "", nil
+	}
+
+	subcommand := args[0]
+	if queue := f.responses[subcommand]; len(queue) > 0 {
+		response := queue[0]
+		if len(queue) > 1 {
+			f.responses[subcommand] = queue[1:]
+		}
+		return response.Output, response.Err
+	}
+
+	switch subcommand {
+	case "new-session", "new-window":
+		f.nextID++
+		return fmt.Sprintf("@%d %%%d\n", f.nextID, f.nextID), nil
+	case "split-window":
+		f.nextID++
+		return fmt.Sprintf("%%%d\n", f.nextID), nil
+	}
+	return "", nil
+}
+
+func (f *FakeRunner) Interactive(args ...string) error {
+	return f.Run(args...)
+}
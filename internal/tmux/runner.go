@@ -0,0 +1,68 @@
+package tmux
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Runner executes tmux with the given arguments. ExecRunner talks to the
+// real tmux binary; FakeRunner records calls so tests can assert the argv.
+type Runner interface {
+	// Run executes a command and discards its output.
+	Run(args ...string) error
+	// Output executes a command and returns its standard output.
+	Output(args ...string) (string, error)
+	// Interactive executes a command connected to the terminal, as needed
+	// for attach-session and switch-client.
+	Interactive(args ...string) error
+}
+
+// ExecRunner runs the tmux binary. SocketName and SocketPath select a server
+// other than the default one, like tmux's -L and -S flags.
+type ExecRunner struct {
+	SocketName string
+	SocketPath string
+}
+
+func (r ExecRunner) command(args []string) *exec.Cmd {
+	var global []string
+	if r.SocketPath != "" {
+		global = []string{"-S", r.SocketPath}
+	} else if r.SocketName != "" {
+		global = []string{"-L", r.SocketName}
+	}
+	return exec.Command("tmux", append(global, args...)...)
+}
+
+func (r ExecRunner) Run(args ...string) error {
+	return r.command(args).Run()
+}
+
+func (r ExecRunner) Output(args ...string) (string, error) {
+	output, err := r.command(args).Output()
+	return string(output), err
+}
+
+func (r ExecRunner) Interactive(args ...string) error {
+	cmd := r.command(args)
+	if len(args) > 0 && args[0] == "attach-session" && os.Getenv("TMUX") != "" {
+		// The client only attaches from inside tmux when the session lives
+		// on another server, which tmux refuses unless TMUX is unset.
+		cmd.Env = withoutEnv(os.Environ(), "TMUX")
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func withoutEnv(env []string, key string) []string {
+	filtered := make([]string, 0, len(env))
+	for _, kv := range env {
+		if !strings.HasPrefix(kv, key+"=") {
+			filtered = append(filtered, kv)
+		}
+	}
+	return filtered
+}
@@ -1,12 +1,15 @@
 package tmux
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"muxyard/internal/config"
 )
@@ -15,6 +18,83 @@ type Session struct {
 	Name     string
 	Windows  int
 	Attached bool
+	Server   string
+	// Activity is when the session was last used.
+	Activity time.Time
+	// Path is the directory the session was started in.
+	Path string
+}
+
+type Window struct {
+	Index  int
+	Name   string
+	Panes  int
+	Active bool
+	Layout string
+}
+
+type Pane struct {
+	WindowIndex int
+	Index       int
+	Command     string
+	Path        string
+	Active      bool
+}
+
+// Client runs tmux operations through a Runner. Each Client talks to one
+// tmux server; Server labels its sessions when several are in use.
+type Client struct {
+	runner Runner
+	Server string
+}
+
+func NewClient(runner Runner) *Client {
+	return &Client{runner: runner}
+}
+
+func NewServerClient(server string, runner Runner) *Client {
+	return &Client{runner: runner, Server: server}
+}
+
+// NewClients returns a client for the configured primary server followed by
+// one for each additional server, skipping servers that repeat the primary.
+func NewClients(cfg config.TmuxConfig) []*Client {
+	primary := config.ServerConfig{
+		Name:       serverLabel(cfg.SocketName, cfg.SocketPath),
+		SocketName: cfg.SocketName,
+		SocketPath: cfg.SocketPath,
+	}
+
+	clients := []*Client{NewServerClient(primary.Name, ExecRunner{SocketName: primary.SocketName, SocketPath: primary.SocketPath})}
+	for _, server := range cfg.Servers {
+		if server.SocketName == primary.SocketName && server.SocketPath == primary.SocketPath {
+			if server.Name != "" {
+				clients[0].Server = server.Name
+			}
+			continue
+		}
+		name := server.Name
+		if name == "" {
+			name = serverLabel(server.SocketName, server.SocketPath)
+		}
+		clients = append(clients, NewServerClient(name, ExecRunner{SocketName: server.SocketName, SocketPath: server.SocketPath}))
+	}
+	return clients
+}
+
+func serverLabel(socketName, socketPath string) string {
+	switch {
+	case socketPath != "":
+		return filepath.Base(socketPath)
+	case socketName != "":
+		return socketName
+	}
+	return "default"
+}
+
+// exitCoder is implemented by *exec.ExitError and FakeExitError.
+type exitCoder interface {
+	ExitCode() int
 }
 
 func IsInsideTmux() bool {
@@ -26,31 +106,34 @@ func IsTmuxAvailable() bool {
 	return err == nil
 }
 
-func ListSessions() ([]Session, error) {
-	cmd := exec.Command("tmux", "list-sessions", "-F", "#{session_name}:#{session_windows}:#{session_attached}")
-	output, err := cmd.Output()
+func (c *Client) ListSessions() ([]Session, error) {
+	output, err := c.runner.Output("list-sessions", "-F", "#{session_name}:#{session_windows}:#{session_attached}:#{session_activity}:#{session_path}")
 	if err != nil {
-		if exitError, ok := err.(*exec.ExitError); ok && exitError.ExitCode() == 1 {
+		var exitError exitCoder
+		if errors.As(err, &exitError) && exitError.ExitCode() == 1 {
 			return []Session{}, nil
 		}
 		return nil, err
 	}
 
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	lines := strings.Split(strings.TrimSpace(output), "\n")
 	sessions := make([]Session, 0, len(lines))
 
 	for _, line := range lines {
 		if line == "" {
 			continue
 		}
-		parts := strings.Split(line, ":")
-		if len(parts) != 3 {
+		// Session names can't contain colons, but the path can
+		parts := strings.SplitN(line, ":", 5)
+		if len(parts) != 5 {
 			continue
 		}
 
 		session := Session{
 			Name:     parts[0],
 			Attached: parts[2] == "1",
+			Server:   c.Server,
+			Path:     parts[4],
 		}
 
 		if parts[1] != "" {
@@ -59,6 +142,9 @@ func ListSessions() ([]Session, error) {
 				session.Windows = windowCount
 			}
 		}
+		if activity, err := strconv.ParseInt(parts[3], 10, 64); err == nil && activity > 0 {
+			session.Activity = time.Unix(activity, 0)
+		}
 
 		sessions = append(sessions, session)
 	}
@@ -66,8 +152,81 @@ func ListSessions() ([]Session, error) {
 	return sessions, nil
 }
 
-func SessionExists(name string) (bool, error) {
-	sessions, err := ListSessions()
+func (c *Client) ListWindows(session string) ([]Window, error) {
+	output, err := c.runner.Output("list-windows", "-t", session, "-F", "#{window_index}\t#{window_name}\t#{window_panes}\t#{window_active}\t#{window_layout}")
+	if err != nil {
+		return nil, err
+	}
+
+	var windows []Window
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		parts := strings.Split(line, "\t")
+		if len(parts) != 5 {
+			continue
+		}
+
+		index, err := strconv.Atoi(parts[0])
+		if err != nil {
+			continue
+		}
+		panes, _ := strconv.Atoi(parts[2])
+
+		windows = append(windows, Window{
+			Index:  index,
+			Name:   parts[1],
+			Panes:  panes,
+			Active: parts[3] == "1",
+			Layout: parts[4],
+		})
+	}
+
+	return windows, nil
+}
+
+// ListPanes returns the panes of every window in a session.
+func (c *Client) ListPanes(session string) ([]Pane, error) {
+	output, err := c.runner.Output("list-panes", "-s", "-t", session, "-F",
+		"#{window_index}\t#{pane_index}\t#{pane_current_command}\t#{pane_current_path}\t#{pane_active}")
+	if err != nil {
+		return nil, err
+	}
+
+	var panes []Pane
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		parts := strings.Split(line, "\t")
+		if len(parts) != 5 {
+			continue
+		}
+
+		windowIndex, err := strconv.Atoi(parts[0])
+		if err != nil {
+			continue
+		}
+		index, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+
+		panes = append(panes, Pane{
+			WindowIndex: windowIndex,
+			Index:       index,
+			Command:     parts[2],
+			Path:        parts[3],
+			Active:      parts[4] == "1",
+		})
+	}
+
+	return panes, nil
+}
+
+// CapturePane returns the visible contents of the target pane, including
+// colour escape sequences. A session name targets its active pane.
+func (c *Client) CapturePane(target string) (string, error) {
+	return c.runner.Output("capture-pane", "-p", "-e", "-t", target)
+}
+
+func (c *Client) SessionExists(name string) (bool, error) {
+	sessions, err := c.ListSessions()
 	if err != nil {
 		return false, err
 	}
@@ -80,85 +239,214 @@ func SessionExists(name string) (bool, error) {
 	return false, nil
 }
 
-func CreateSession(name, path string, template *config.SessionTemplate) error {
-	if len(template.Windows) == 0 {
-		return fmt.Errorf("template must have at least one window")
+func (c *Client) CreateSession(name, path string, template *config.SessionTemplate) error {
+	if err := checkTemplate(template); err != nil {
+		return err
 	}
 
-	firstWindow := template.Windows[0]
-	args := []string{"new-session", "-d", "-s", name, "-c", path}
-
-	if firstWindow.Name != "" {
-		args = append(args, "-n", firstWindow.Name)
+	// Once the session exists, a failure kills it rather than leave it
+	// half built
+	fail := func(err error) error {
+		c.runner.Run("kill-session", "-t", name)
+		return err
 	}
 
-	// Use shell wrapper for command to keep shell alive after command exits
-	if firstWindow.Command != "" {
-		shellCmd := fmt.Sprintf("%s; exec $SHELL", firstWindow.Command)
-		args = append(args, "sh", "-c", shellCmd)
-	}
+	for i, window := range template.Windows {
+		panes := windowPanes(window)
+		windowPath := resolvePath(path, window.Path)
 
-	cmd := exec.Command("tmux", args...)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to create session: %w", err)
-	}
-
-	// Create additional windows
-	for i, window := range template.Windows[1:] {
-		windowArgs := []string{"new-window", "-t", name, "-c", path}
+		var args []string
+		if i == 0 {
+			args = []string{"new-session", "-d", "-s", name}
+		} else {
+			args = []string{"new-window", "-t", name}
+		}
+		args = append(args, "-P", "-F", "#{window_id} #{pane_id}", "-c", resolvePath(windowPath, panes[0].Path))
 
 		if window.Name != "" {
-			windowArgs = append(windowArgs, "-n", window.Name)
+			args = append(args, "-n", window.Name)
+		}
+		args = append(args, shellCommand(panes[0].Command)...)
+
+		output, err := c.runner.Output(args...)
+		if err != nil {
+			if i == 0 {
+				return fmt.Errorf("failed to create session: %w", err)
+			}
+			return fail(fmt.Errorf("failed to create window %d: %w", i+1, err))
+		}
+
+		ids := strings.Fields(output)
+		if len(ids) != 2 {
+			return fail(fmt.Errorf("unexpected output from tmux when creating window %d: %q", i+1, output))
+		}
+		windowID := ids[0]
+
+		focusPane := ""
+		if panes[0].Focus {
+			focusPane = ids[1]
+		}
+
+		for j, pane := range panes[1:] {
+			splitArgs, err := splitWindowArgs(windowID, resolvePath(windowPath, pane.Path), pane)
+			if err != nil {
+				return fail(fmt.Errorf("window %d pane %d: %w", i+1, j+2, err))
+			}
+
+			output, err := c.runner.Output(splitArgs...)
+			if err != nil {
+				return fail(fmt.Errorf("failed to create pane %d in window %d: %w", j+2, i+1, err))
+			}
+
+			if pane.Focus {
+				focusPane = strings.TrimSpace(output)
+			}
 		}
 
-		// Use shell wrapper for command to keep shell alive after command exits
-		if window.Command != "" {
-			shellCmd := fmt.Sprintf("%s; exec $SHELL", window.Command)
-			windowArgs = append(windowArgs, "sh", "-c", shellCmd)
+		if window.Layout != "" {
+			if err := c.runner.Run("select-layout", "-t", windowID, window.Layout); err != nil {
+				return fail(fmt.Errorf("failed to apply layout %q to window %d: %w", window.Layout, i+1, err))
+			}
 		}
 
-		cmd := exec.Command("tmux", windowArgs...)
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("failed to create window %d: %w", i+2, err)
+		if focusPane != "" {
+			c.runner.Run("select-pane", "-t", focusPane) // Don't fail if this doesn't work
 		}
 	}
 
 	// Focus the specified window if provided
 	if template.FocusedWindow != "" {
-		focusCmd := exec.Command("tmux", "select-window", "-t", name+":"+template.FocusedWindow)
-		focusCmd.Run() // Don't fail if this doesn't work
+		c.runner.Run("select-window", "-t", name+":"+template.FocusedWindow) // Don't fail if this doesn't work
 	}
 
 	return nil
 }
 
-func AttachToSession(name string) error {
-	var cmd *exec.Cmd
-	if IsInsideTmux() {
-		cmd = exec.Command("tmux", "switch-client", "-t", name)
+var paneSizePattern = regexp.MustCompile(`^[0-9]+%?$`)
+
+// checkTemplate finds what tmux would only reject halfway through creating
+// a session from the template: no windows, unknown split directions, sizes
+// that are neither a number of lines or columns nor a percentage, and more
+// than one focused pane in a window.
+func checkTemplate(template *config.SessionTemplate) error {
+	if len(template.Windows) == 0 {
+		return fmt.Errorf("template must have at least one window")
+	}
+
+	for i, window := range template.Windows {
+		focused := 0
+		for j, pane := range windowPanes(window) {
+			if pane.Focus {
+				focused++
+			}
+			if j == 0 {
+				continue
+			}
+			if _, err := pane.SplitHorizontal(); err != nil {
+				return fmt.Errorf("window %d pane %d: %w", i+1, j+1, err)
+			}
+			if pane.Size != "" && !paneSizePattern.MatchString(pane.Size) {
+				return fmt.Errorf("window %d pane %d: size must be a number or a percentage, got %q", i+1, j+1, pane.Size)
+			}
+		}
+		if focused > 1 {
+			return fmt.Errorf("window %d: %d panes have focus, only one can", i+1, focused)
+		}
+	}
+	return nil
+}
+
+// windowPanes returns the panes to create for a window. A window without
+// explicit panes is a single pane running the window's command.
+func windowPanes(window config.WindowConfig) []config.PaneConfig {
+	if len(window.Panes) == 0 {
+		return []config.PaneConfig{{Command: window.Command}}
+	}
+	return window.Panes
+}
+
+func splitWindowArgs(target, path string, pane config.PaneConfig) ([]string, error) {
+	args := []string{"split-window", "-t", target, "-P", "-F", "#{pane_id}", "-c", path}
+
+	horizontal, err := pane.SplitHorizontal()
+	if err != nil {
+		return nil, err
+	}
+	if horizontal {
+		args = append(args, "-h")
 	} else {
-		cmd = exec.Command("tmux", "attach-session", "-t", name)
+		args = append(args, "-v")
 	}
 
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	if pane.Size != "" {
+		args = append(args, "-l", pane.Size)
+	}
 
-	return cmd.Run()
+	return append(args, shellCommand(pane.Command)...), nil
 }
 
-func RenameSession(oldName, newName string) error {
-	cmd := exec.Command("tmux", "rename-session", "-t", oldName, newName)
-	return cmd.Run()
+// shellCommand wraps a command so the shell stays alive after it exits.
+func shellCommand(command string) []string {
+	if command == "" {
+		return nil
+	}
+	return []string{"sh", "-c", fmt.Sprintf("%s; exec $SHELL", command)}
 }
 
-func KillSession(name string) error {
-	cmd := exec.Command("tmux", "kill-session", "-t", name)
-	return cmd.Run()
+// resolvePath resolves a window or pane path relative to its parent
+// directory, expanding a leading ~ to the home directory.
+func resolvePath(base, path string) string {
+	if path == "" {
+		return base
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(base, path)
+}
+
+// AttachToSession attaches or switches to a session. The name may also be a
+// session:window or session:window.pane target to land on that window or pane.
+func (c *Client) AttachToSession(name string) error {
+	if IsInsideTmux() && c.isCurrentServer() {
+		return c.runner.Interactive("switch-client", "-t", name)
+	}
+	return c.runner.Interactive("attach-session", "-t", name)
+}
+
+// isCurrentServer reports whether the client talks to the tmux server
+// muxyard is running inside of, since switch-client can't cross servers.
+func (c *Client) isCurrentServer() bool {
+	current := strings.SplitN(os.Getenv("TMUX"), ",", 2)[0]
+	output, err := c.runner.Output("display-message", "-p", "#{socket_path}")
+	if err != nil {
+		return true
+	}
+	return strings.TrimSpace(output) == current
+}
+
+func (c *Client) RenameSession(oldName, newName string) error {
+	return c.runner.Run("rename-session", "-t", oldName, newName)
+}
+
+func (c *Client) KillSession(name string) error {
+	return c.runner.Run("kill-session", "-t", name)
 }
 
 func GenerateSessionName(repoPath string, existingSessions []Session) string {
-	baseName := filepath.Base(repoPath)
+	return UniqueSessionName(filepath.Base(repoPath), existingSessions)
+}
+
+// UniqueSessionName makes baseName a valid session name, replacing the
+// '.' and ':' that tmux doesn't allow, and numbers it if a session of that
+// name exists.
+func UniqueSessionName(baseName string, existingSessions []Session) string {
+	baseName = strings.NewReplacer(".", "_", ":", "_").Replace(baseName)
 	name := baseName
 
 	counter := 1
@@ -1,7 +1,13 @@
 package tmux
 
 import (
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"muxyard/internal/config"
 )
 
 func TestIsTmuxAvailable(t *testing.T) {
@@ -30,6 +36,7 @@ func TestGenerateSessionName(t *testing.T) {
 		{"/home/user/myproject", "myproject"},
 		{"/home/user/test", "test_3"},
 		{"/tmp/newproject", "newproject"},
+		{"/home/user/site.io", "site_io"},
 	}
 
 	for _, tt := range tests {
@@ -39,3 +46,226 @@ func TestGenerateSessionName(t *testing.T) {
 		}
 	}
 }
+
+func TestSplitWindowArgs(t *testing.T) {
+	tests := []struct {
+		pane     config.PaneConfig
+		expected []string
+	}{
+		{
+			config.PaneConfig{},
+			[]string{"split-window", "-t", "@1", "-P", "-F", "#{pane_id}", "-c", "/src", "-v"},
+		},
+		{
+			config.PaneConfig{Split: "horizontal", Size: "30%", Command: "make watch"},
+			[]string{"split-window", "-t", "@1", "-P", "-F", "#{pane_id}", "-c", "/src", "-h", "-l", "30%", "sh", "-c", "make watch; exec $SHELL"},
+		},
+	}
+
+	for _, tt := range tests {
+		result, err := splitWindowArgs("@1", "/src", tt.pane)
+		if err != nil {
+			t.Fatalf("splitWindowArgs(%+v) failed: %v", tt.pane, err)
+		}
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("splitWindowArgs(%+v) = %q, want %q", tt.pane, result, tt.expected)
+		}
+	}
+
+	if _, err := splitWindowArgs("@1", "/src", config.PaneConfig{Split: "diagonal"}); err == nil {
+		t.Error("Expected error for unknown split direction")
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	tests := []struct {
+		base     string
+		path     string
+		expected string
+	}{
+		{"/src/api", "", "/src/api"},
+		{"/src/api", "web", "/src/api/web"},
+		{"/src/api", "/var/log", "/var/log"},
+	}
+
+	for _, tt := range tests {
+		result := resolvePath(tt.base, tt.path)
+		if result != tt.expected {
+			t.Errorf("resolvePath(%q, %q) = %q, want %q", tt.base, tt.path, result, tt.expected)
+		}
+	}
+}
+
+func TestCreateSession(t *testing.T) {
+	runner := NewFakeRunner()
+	client := NewClient(runner)
+
+	template := &config.SessionTemplate{
+		FocusedWindow: "editor",
+		Windows: []config.WindowConfig{
+			{Name: "editor", Command: "nvim ."},
+			{
+				Name:   "dev",
+				Layout: "main-vertical",
+				Panes: []config.PaneConfig{
+					{Command: "make run"},
+					{Split: "horizontal", Size: "40%", Path: "web", Focus: true},
+				},
+			},
+		},
+	}
+
+	if err := client.CreateSession("api", "/src/api", template); err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	expected := []string{
+		"new-session -d -s api -P -F #{window_id} #{pane_id} -c /src/api -n editor sh -c nvim .; exec $SHELL",
+		"new-window -t api -P -F #{window_id} #{pane_id} -c /src/api -n dev sh -c make run; exec $SHELL",
+		"split-window -t @2 -P -F #{pane_id} -c /src/api/web -h -l 40%",
+		"select-layout -t @2 main-vertical",
+		"select-pane -t %3",
+		"select-window -t api:editor",
+	}
+	if !reflect.DeepEqual(runner.Commands(), expected) {
+		t.Errorf("CreateSession ran\n%s\nwant\n%s", strings.Join(runner.Commands(), "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestCreateSessionInvalidTemplate(t *testing.T) {
+	split := func(panes ...config.PaneConfig) *config.SessionTemplate {
+		return &config.SessionTemplate{Windows: []config.WindowConfig{{Name: "a"}, {Name: "b", Panes: panes}}}
+	}
+	tests := map[string]*config.SessionTemplate{
+		"no windows":      {},
+		"split direction": split(config.PaneConfig{}, config.PaneConfig{Split: "diagonal"}),
+		"size":            split(config.PaneConfig{}, config.PaneConfig{Size: "half"}),
+		"focus":           split(config.PaneConfig{Focus: true}, config.PaneConfig{Focus: true}),
+	}
+
+	for name, template := range tests {
+		runner := NewFakeRunner()
+		client := NewClient(runner)
+		if err := client.CreateSession("api", "/src/api", template); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		if len(runner.Calls) != 0 {
+			t.Errorf("%s: expected no tmux calls, got %q", name, runner.Commands())
+		}
+	}
+}
+
+func TestCreateSessionCleansUp(t *testing.T) {
+	runner := NewFakeRunner()
+	runner.Respond("new-window", "", errors.New("no space for new pane"))
+	client := NewClient(runner)
+
+	template := &config.SessionTemplate{Windows: []config.WindowConfig{{Name: "a"}, {Name: "b"}}}
+	if err := client.CreateSession("api", "/src/api", template); err == nil {
+		t.Fatal("Expected the failed window to fail the session")
+	}
+	commands := runner.Commands()
+	if last := commands[len(commands)-1]; last != "kill-session -t api" {
+		t.Errorf("Expected the half-built session to be killed, got\n%s", strings.Join(commands, "\n"))
+	}
+
+	runner = NewFakeRunner()
+	runner.Respond("new-session", "", errors.New("duplicate session: api"))
+	if err := NewClient(runner).CreateSession("api", "/src/api", template); err == nil || len(runner.Calls) != 1 {
+		t.Errorf("Expected nothing to clean up when the session wasn't created, got %q (err %v)", runner.Commands(), err)
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	runner := NewFakeRunner()
+	runner.Respond("list-sessions", "api:3:1:1714564800:/src/api\nweb:1:0::/src/web\n", nil)
+	client := NewClient(runner)
+
+	sessions, err := client.ListSessions()
+	if err != nil {
+		t.Fatalf("ListSessions failed: %v", err)
+	}
+
+	expected := []Session{
+		{Name: "api", Windows: 3, Attached: true, Activity: time.Unix(1714564800, 0), Path: "/src/api"},
+		{Name: "web", Windows: 1, Attached: false, Path: "/src/web"},
+	}
+	if !reflect.DeepEqual(sessions, expected) {
+		t.Errorf("ListSessions() = %+v, want %+v", sessions, expected)
+	}
+}
+
+func TestListSessionsNoServer(t *testing.T) {
+	runner := NewFakeRunner()
+	runner.Respond("list-sessions", "", &FakeExitError{Code: 1})
+	client := NewClient(runner)
+
+	sessions, err := client.ListSessions()
+	if err != nil {
+		t.Fatalf("ListSessions failed: %v", err)
+	}
+	if len(sessions) != 0 {
+		t.Errorf("Expected no sessions, got %+v", sessions)
+	}
+}
+
+func TestAttachToSessionAcrossServers(t *testing.T) {
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,1234,0")
+
+	runner := NewFakeRunner()
+	runner.Respond("display-message", "/tmp/tmux-1000/default\n", nil)
+	if err := NewClient(runner).AttachToSession("api"); err != nil {
+		t.Fatal(err)
+	}
+	if got := runner.Commands()[1]; got != "switch-client -t api" {
+		t.Errorf("Expected switch-client on the current server, got %q", got)
+	}
+
+	runner = NewFakeRunner()
+	runner.Respond("display-message", "/tmp/tmux-1000/work\n", nil)
+	if err := NewClient(runner).AttachToSession("api"); err != nil {
+		t.Fatal(err)
+	}
+	if got := runner.Commands()[1]; got != "attach-session -t api" {
+		t.Errorf("Expected attach-session on another server, got %q", got)
+	}
+}
+
+func TestNewClients(t *testing.T) {
+	clients := NewClients(config.TmuxConfig{
+		SocketName: "work",
+		Servers: []config.ServerConfig{
+			{Name: "office", SocketName: "work"},
+			{Name: "scratch", SocketPath: "/tmp/scratch.sock"},
+			{SocketName: "personal"},
+		},
+	})
+
+	var names []string
+	for _, client := range clients {
+		names = append(names, client.Server)
+	}
+	expected := []string{"office", "scratch", "personal"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("NewClients() servers = %q, want %q", names, expected)
+	}
+}
+
+func TestListPanes(t *testing.T) {
+	runner := NewFakeRunner()
+	runner.Respond("list-panes", "0\t0\tnvim\t/src/api\t1\n1\t0\tzsh\t/src/api\t0\n1\t1\tgo\t/src/api/cmd\t1\n", nil)
+
+	panes, err := NewClient(runner).ListPanes("api")
+	if err != nil {
+		t.Fatalf("ListPanes failed: %v", err)
+	}
+
+	expected := []Pane{
+		{WindowIndex: 0, Index: 0, Command: "nvim", Path: "/src/api", Active: true},
+		{WindowIndex: 1, Index: 0, Command: "zsh", Path: "/src/api", Active: false},
+		{WindowIndex: 1, Index: 1, Command: "go", Path: "/src/api/cmd", Active: true},
+	}
+	if !reflect.DeepEqual(panes, expected) {
+		t.Errorf("ListPanes() = %+v, want %+v", panes, expected)
+	}
+}
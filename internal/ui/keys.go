@@ -0,0 +1,171 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"muxyard/internal/config"
+)
+
+// KeyMap holds the binding of every action. Views check only the actions
+// they offer, so the same key can mean different things in different views.
+type KeyMap struct {
+	Up             key.Binding
+	Down           key.Binding
+	Select         key.Binding
+	Back           key.Binding
+	MenuBack       key.Binding
+	Quit           key.Binding
+	Attach         key.Binding
+	Create         key.Binding
+	Rename         key.Binding
+	Delete         key.Binding
+	Filter         key.Binding
+	Visual         key.Binding
+	Tree           key.Binding
+	Preview        key.Binding
+	Expand         key.Binding
+	Collapse       key.Binding
+	Submit         key.Binding
+	Cancel         key.Binding
+	Confirm        key.Binding
+	Deny           key.Binding
+	Sort           key.Binding
+	DirtyOnly      key.Binding
+	AddWorktree    key.Binding
+	RemoveWorktree key.Binding
+	Rescan         key.Binding
+}
+
+// NewKeyMap builds the bindings from the keys section of the config, using
+// the default keys for the actions it doesn't set.
+func NewKeyMap(keys config.KeysConfig) KeyMap {
+	bind := func(configured config.KeyList, defaults ...string) key.Binding {
+		if configured == nil {
+			configured = defaults
+		}
+		// Key messages spell the space bar as a literal space
+		bound := make([]string, len(configured))
+		for i, k := range configured {
+			if k == "space" {
+				k = " "
+			}
+			bound[i] = k
+		}
+		return key.NewBinding(key.WithKeys(bound...))
+	}
+
+	return KeyMap{
+		Up:             bind(keys.Up, "k", "up"),
+		Down:           bind(keys.Down, "j", "down"),
+		Select:         bind(keys.Select, "enter", "l"),
+		Back:           bind(keys.Back, "esc", "q"),
+		MenuBack:       bind(keys.MenuBack, "esc", "q", "h"),
+		Quit:           bind(keys.Quit, "q", "ctrl+c"),
+		Attach:         bind(keys.Attach, "enter", "l"),
+		Create:         bind(keys.Create, "c", "n"),
+		Rename:         bind(keys.Rename, "r"),
+		Delete:         bind(keys.Delete, "d", "x"),
+		Filter:         bind(keys.Filter, "/"),
+		Visual:         bind(keys.Visual, "ctrl+v"),
+		Tree:           bind(keys.Tree, "t"),
+		Preview:        bind(keys.Preview, "p"),
+		Expand:         bind(keys.Expand, "l", "right", "space"),
+		Collapse:       bind(keys.Collapse, "h", "left"),
+		Submit:         bind(keys.Submit, "enter"),
+		Cancel:         bind(keys.Cancel, "esc"),
+		Confirm:        bind(keys.Confirm, "y", "Y"),
+		Deny:           bind(keys.Deny, "n", "N"),
+		Sort:           bind(keys.Sort, "s"),
+		DirtyOnly:      bind(keys.DirtyOnly, "D"),
+		AddWorktree:    bind(keys.AddWorktree, "w"),
+		RemoveWorktree: bind(keys.RemoveWorktree, "W"),
+		Rescan:         bind(keys.Rescan, "ctrl+r"),
+	}
+}
+
+// listKeyMap makes the keys the list handles itself follow the bindings,
+// so that keys freed up by rebinding don't fall through to the list's
+// defaults, which would otherwise quit on 'q'.
+func listKeyMap(listKeys list.KeyMap, keys KeyMap) list.KeyMap {
+	listKeys.CursorUp = keys.Up
+	listKeys.CursorDown = keys.Down
+	listKeys.Quit = keys.Quit
+	return listKeys
+}
+
+// keysText shows a binding's keys the way the help footer spells them.
+// Arrow keys are left out when there are others, as the letters are what
+// the footer is there to teach.
+func keysText(b key.Binding) string {
+	var keys []string
+	for _, k := range b.Keys() {
+		switch {
+		case k == "up" || k == "down" || k == "left" || k == "right":
+			if len(b.Keys()) > 1 {
+				continue
+			}
+		case k == " ":
+			k = "space"
+		case len(k) == 1 && k != strings.ToLower(k) && slices.Contains(b.Keys(), strings.ToLower(k)):
+			// 'Y' needs no mention next to 'y'
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return strings.Join(keys, "/")
+}
+
+// either combines bindings into one that matches the keys of all of them,
+// for hints covering several actions.
+func either(bindings ...key.Binding) key.Binding {
+	var keys []string
+	for _, b := range bindings {
+		keys = append(keys, b.Keys()...)
+	}
+	return key.NewBinding(key.WithKeys(keys...))
+}
+
+// without drops the keys that an earlier checked binding takes, so that a
+// hint shows only the keys that actually reach the action.
+func without(b, taken key.Binding) key.Binding {
+	var keys []string
+	for _, k := range b.Keys() {
+		if !slices.Contains(taken.Keys(), k) {
+			keys = append(keys, k)
+		}
+	}
+	return key.NewBinding(key.WithKeys(keys...))
+}
+
+// hint describes one binding for the help footer, e.g. "'d/x' delete".
+// Disabled bindings have no hint.
+func hint(b key.Binding, desc string) string {
+	if len(b.Keys()) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("'%s' %s", keysText(b), desc)
+}
+
+// navHint describes the up and down bindings together, e.g. "'j/k' navigate".
+func (k KeyMap) navHint(desc string) string {
+	down, up := keysText(k.Down), keysText(k.Up)
+	if down == "" || up == "" {
+		return hint(k.Down, desc) + hint(k.Up, desc)
+	}
+	return fmt.Sprintf("'%s/%s' %s", strings.Split(down, "/")[0], strings.Split(up, "/")[0], desc)
+}
+
+// helpLine joins hints into a help footer.
+func helpLine(hints ...string) string {
+	var shown []string
+	for _, h := range hints {
+		if h != "" {
+			shown = append(shown, h)
+		}
+	}
+	return "\n" + strings.Join(shown, " • ")
+}
@@ -1,18 +1,26 @@
 package ui
 
 import (
+	"cmp"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 	"github.com/sahilm/fuzzy"
 	"muxyard/internal/config"
+	"muxyard/internal/frecency"
 	"muxyard/internal/git"
+	"muxyard/internal/snapshot"
 	"muxyard/internal/tmux"
 )
 
@@ -28,6 +36,11 @@ const (
 	renameSessionView
 	loadingView
 	confirmDeleteView
+	treeView
+	restoreListView
+	templateParamsView
+	worktreeBranchView
+	confirmRemoveWorktreeView
 )
 
 type listItem struct {
@@ -42,6 +55,7 @@ func (i listItem) FilterValue() string { return i.title }
 
 type MainModel struct {
 	cfg              *config.Config
+	servers          []*tmux.Client
 	styles           Styles
 	state            viewState
 	list             list.Model
@@ -71,15 +85,50 @@ type MainModel struct {
 	visualStart      int
 	confirmDelete    bool
 	deleteTarget     string
+	deleteSessions   []tmux.Session
+	showPreview      bool
+	previewSession   tmux.Session
+	previewContent   string
+	previewWindows   []tmux.Window
+	previewError     string
+	treeExpanded     map[string]bool
+	trees            map[string]sessionTree
+	savedSessions    []snapshot.Session
+	projectTemplate  *config.SessionTemplate
+	configIssues     []config.Issue
+	configWatcher    *config.Watcher
+	keys             KeyMap
+	paramTemplate    *config.SessionTemplate
+	paramIndex       int
+	paramValues      map[string]string
+	worktreeMain     string
+	removeTarget     *git.Repository
+	repoStatus       map[string]repoStatus
+	repoSort         sortMode
+	sessionSort      sortMode
+	frecency         *frecency.Store
+	dirtyOnly        bool
+	scanning         bool
+	rescanning       bool
+	// serverErrors are the servers whose sessions couldn't be listed on the
+	// last load; they stay shown until a load reaches them again.
+	serverErrors []string
 }
 
-type sessionsLoadedMsg []tmux.Session
+// sessionsLoadedMsg lists the sessions of every server that answered, and
+// why the others didn't.
+type sessionsLoadedMsg struct {
+	sessions []tmux.Session
+	failed   []string
+}
 type reposLoadedMsg []git.Repository
 type errorMsg string
 type successMsg string
 
-func NewMainModel(cfg *config.Config) MainModel {
-	styles := NewStyles(cfg.Colors)
+// NewMainModel creates the TUI model. New sessions are created on the first
+// server; sessions from all servers are listed.
+func NewMainModel(cfg *config.Config, servers []*tmux.Client) MainModel {
+	styles := NewStyles(cfg.Theme, cfg.Colors)
 
 	s := spinner.New()
 	s.Spinner = spinner.Dot
@@ -93,18 +142,20 @@ func NewMainModel(cfg *config.Config) MainModel {
 	pathInput.Placeholder = "Directory path (e.g., ~/projects/myapp)"
 	pathInput.CharLimit = 200
 
+	keys := NewKeyMap(cfg.Keys)
+
+	store, historyErr := loadFrecency()
+
 	// Custom list with disabled default filtering
 	l := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
 	l.Title = "Tmux Sessions"
 	l.SetShowStatusBar(false)
 	l.SetFilteringEnabled(false) // We'll handle filtering ourselves
-	l.KeyMap.Quit.SetKeys("q", "ctrl+c")
-	// Remove conflicting keybindings
-	l.KeyMap.CursorUp.SetKeys("up", "k")
-	l.KeyMap.CursorDown.SetKeys("down", "j")
+	l.KeyMap = listKeyMap(l.KeyMap, keys)
 
 	return MainModel{
 		cfg:              cfg,
+		servers:          servers,
 		styles:           styles,
 		state:            sessionListView,
 		list:             l,
@@ -113,32 +164,49 @@ func NewMainModel(cfg *config.Config) MainModel {
 		pathInput:        pathInput,
 		templates:        cfg.Templates,
 		selectedSessions: make(map[int]bool),
+		repoStatus:       make(map[string]repoStatus),
+		showPreview:      true,
+		configIssues:     cfg.Issues,
+		configWatcher:    config.NewWatcher(),
+		keys:             keys,
+		frecency:         store,
+		error:            historyErr,
 	}
 }
 
 func (m MainModel) Init() tea.Cmd {
 	return tea.Batch(
 		m.spinner.Tick,
-		loadSessions,
+		loadSessions(m.servers),
+		previewTick(),
+		watchConfig(m.configWatcher),
 	)
 }
 
-func loadSessions() tea.Msg {
-	sessions, err := tmux.ListSessions()
-	if err != nil {
-		return errorMsg(fmt.Sprintf("Failed to load sessions: %v", err))
+func loadSessions(servers []*tmux.Client) tea.Cmd {
+	return func() tea.Msg {
+		var msg sessionsLoadedMsg
+		for _, server := range servers {
+			// One server being down shouldn't hide the others' sessions
+			serverSessions, err := server.ListSessions()
+			if err != nil {
+				msg.failed = append(msg.failed, fmt.Sprintf("Failed to load sessions from %s: %v", server.Server, err))
+				continue
+			}
+			msg.sessions = append(msg.sessions, serverSessions...)
+		}
+		return msg
 	}
-	return sessionsLoadedMsg(sessions)
 }
 
-func loadRepositories(directories []string) tea.Cmd {
-	return func() tea.Msg {
-		repos, err := git.FindRepositories(directories)
-		if err != nil {
-			return errorMsg(fmt.Sprintf("Failed to find repositories: %v", err))
+// client returns the client for the server a session lives on.
+func (m MainModel) client(session tmux.Session) *tmux.Client {
+	for _, server := range m.servers {
+		if server.Server == session.Server {
+			return server
 		}
-		return reposLoadedMsg(repos)
 	}
+	return m.servers[0]
 }
 
 func (m MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -153,11 +221,17 @@ func (m MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	}
 
+	// The config warning stays up until the first key press
+	if _, ok := msg.(tea.KeyMsg); ok && len(m.configIssues) > 0 {
+		m.configIssues = nil
+		m = m.resize()
+	}
+
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
-		m.list.SetSize(msg.Width-4, msg.Height-8)
+		m = m.resize()
 
 	case tea.KeyMsg:
 		if m.quitting {
@@ -181,17 +255,60 @@ func (m MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.handleRenameSessionKeys(msg)
 		case confirmDeleteView:
 			return m.handleConfirmDeleteKeys(msg)
+		case treeView:
+			return m.handleTreeKeys(msg)
+		case restoreListView:
+			return m.handleRestoreKeys(msg)
+		case templateParamsView:
+			return m.handleTemplateParamsKeys(msg)
+		case worktreeBranchView:
+			return m.handleWorktreeBranchKeys(msg)
+		case confirmRemoveWorktreeView:
+			return m.handleConfirmRemoveWorktreeKeys(msg)
 		}
 
 	case sessionsLoadedMsg:
-		m.sessions = []tmux.Session(msg)
-		m.filteredSessions = m.sessions
-		return m.updateSessionList(), nil
+		m.sessions = msg.sessions
+		m.serverErrors = msg.failed
+		m = m.filterSessions()
+		m = m.updateSessionList()
+		return m, m.refreshPreview()
 
 	case reposLoadedMsg:
-		m.repos = []git.Repository(msg)
-		m.filteredRepos = m.repos
-		return m.updateRepoList(), nil
+		return m.applyRepos(msg)
+
+	case repoStatusMsg:
+		return m.applyRepoStatus(msg), nil
+
+	case previewLoadedMsg:
+		// Drop previews that arrive after the cursor has moved on
+		if session, ok := m.selectedSessionForPreview(); ok && sameSession(session, msg.session) {
+			m.previewSession = msg.session
+			m.previewContent = msg.content
+			m.previewWindows = msg.windows
+			m.previewError = ""
+			if msg.err != nil {
+				m.previewError = fmt.Sprintf("Failed to capture pane: %v", msg.err)
+			}
+		}
+		return m, nil
+
+	case treeLoadedMsg:
+		if msg.err != nil {
+			m.error = fmt.Sprintf("Failed to load windows for %s: %v", msg.session.Name, msg.err)
+			return m, nil
+		}
+		if m.state == treeView {
+			m.trees[treeNode{session: msg.session}.key()] = msg.tree
+			return m.updateTreeList(), nil
+		}
+		return m, nil
+
+	case previewTickMsg:
+		return m, tea.Batch(m.refreshPreview(), previewTick())
+
+	case configCheckedMsg:
+		return m.applyConfig(msg), watchConfig(m.configWatcher)
 
 	case errorMsg:
 		m.error = string(msg)
@@ -216,16 +333,16 @@ func (m MainModel) handleSessionListKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	// Handle input when in filter mode first
 	if m.inputFocused {
-		switch msg.String() {
-		case "esc":
+		switch {
+		case key.Matches(msg, m.keys.Cancel):
 			m.inputFocused = false
 			m.nameInput.Blur()
 			m.filterQuery = ""
-			m.filteredSessions = m.sessions
+			m = m.filterSessions()
 			return m.updateSessionList(), nil
-		case "enter":
+		case key.Matches(msg, m.keys.Submit):
 			m.filterQuery = m.nameInput.Value()
-			m.filteredSessions = m.fuzzyFilterSessions(m.filterQuery)
+			m = m.filterSessions()
 			m.inputFocused = false
 			m.nameInput.Blur()
 			return m.updateSessionList(), nil
@@ -233,14 +350,15 @@ func (m MainModel) handleSessionListKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			// Update input and apply real-time filtering
 			m.nameInput, cmd = m.nameInput.Update(msg)
 			m.filterQuery = m.nameInput.Value()
-			m.filteredSessions = m.fuzzyFilterSessions(m.filterQuery)
-			return m.updateSessionList(), cmd
+			m = m.filterSessions()
+			m = m.updateSessionList()
+			return m, tea.Batch(cmd, m.refreshPreview())
 		}
 	}
 
 	// Handle normal navigation and commands when not in filter mode
-	switch msg.String() {
-	case "q", "ctrl+c":
+	switch {
+	case key.Matches(msg, m.keys.Quit):
 		if m.visualMode {
 			// Exit visual mode
 			m.visualMode = false
@@ -250,7 +368,7 @@ func (m MainModel) handleSessionListKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.quitting = true
 		return m, tea.Quit
 
-	case "esc":
+	case key.Matches(msg, m.keys.Cancel):
 		if m.visualMode {
 			// Exit visual mode
 			m.visualMode = false
@@ -258,7 +376,18 @@ func (m MainModel) handleSessionListKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m.updateSessionList(), nil
 		}
 
-	case "/":
+	case key.Matches(msg, m.keys.Tree):
+		if !m.visualMode && len(m.filteredSessions) > 0 {
+			return m.enterTree()
+		}
+
+	case key.Matches(msg, m.keys.Preview):
+		if !m.visualMode {
+			m.showPreview = !m.showPreview
+			return m, m.refreshPreview()
+		}
+
+	case key.Matches(msg, m.keys.Filter):
 		if !m.visualMode {
 			// Enter filter mode
 			m.inputFocused = true
@@ -267,31 +396,41 @@ func (m MainModel) handleSessionListKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
-	case "enter", "l":
+	case key.Matches(msg, m.keys.Attach):
 		if !m.visualMode {
 			// Attach to session
 			if len(m.filteredSessions) > 0 {
 				selectedIdx := m.list.Index()
 				if selectedIdx >= 0 && selectedIdx < len(m.filteredSessions) {
 					session := m.filteredSessions[selectedIdx]
-					err := tmux.AttachToSession(session.Name)
+					visit := m.recordVisit(session.Server, session.Name, "")
+					err := m.client(session).AttachToSession(session.Name)
 					if err != nil {
 						m.error = fmt.Sprintf("Failed to attach: %v", err)
-					} else {
-						m.quitting = true
-						return m, tea.Quit
+						return m, visit
 					}
+					m.quitting = true
+					return m, tea.Sequence(visit, tea.Quit)
 				}
 			}
 		}
 
-	case "c", "n":
+	case key.Matches(msg, m.keys.Sort):
+		if !m.visualMode {
+			m.sessionSort = nextSort(sessionSorts, m.sessionSort)
+			m = m.filterSessions()
+			m.list.Select(0)
+			m = m.updateSessionList()
+			return m, m.refreshPreview()
+		}
+
+	case key.Matches(msg, m.keys.Create):
 		if !m.inputFocused && !m.visualMode {
 			m.state = createModeView
 			return m.updateCreateModeList(), nil
 		}
 
-	case "r":
+	case key.Matches(msg, m.keys.Rename):
 		if !m.inputFocused && !m.visualMode && len(m.filteredSessions) > 0 {
 			selectedIdx := m.list.Index()
 			if selectedIdx >= 0 && selectedIdx < len(m.filteredSessions) {
@@ -304,7 +443,7 @@ func (m MainModel) handleSessionListKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			}
 		}
 
-	case "ctrl+v":
+	case key.Matches(msg, m.keys.Visual):
 		if !m.inputFocused {
 			// Toggle visual mode
 			m.visualMode = !m.visualMode
@@ -320,7 +459,7 @@ func (m MainModel) handleSessionListKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m.updateSessionList(), nil
 		}
 
-	case "j", "down":
+	case key.Matches(msg, m.keys.Down):
 		if !m.inputFocused {
 			if m.visualMode {
 				// Move cursor and update selection
@@ -328,15 +467,15 @@ func (m MainModel) handleSessionListKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				if newIdx < len(m.filteredSessions) {
 					m.list.CursorDown()
 					m.updateVisualSelection()
-					return m.updateSessionList(), nil
+					m = m.updateSessionList()
 				}
 			} else {
 				m.list.CursorDown()
 			}
 		}
-		return m, nil
+		return m, m.refreshPreview()
 
-	case "k", "up":
+	case key.Matches(msg, m.keys.Up):
 		if !m.inputFocused {
 			if m.visualMode {
 				// Move cursor and update selection
@@ -344,15 +483,15 @@ func (m MainModel) handleSessionListKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				if newIdx >= 0 {
 					m.list.CursorUp()
 					m.updateVisualSelection()
-					return m.updateSessionList(), nil
+					m = m.updateSessionList()
 				}
 			} else {
 				m.list.CursorUp()
 			}
 		}
-		return m, nil
+		return m, m.refreshPreview()
 
-	case "d", "x":
+	case key.Matches(msg, m.keys.Delete):
 		if !m.inputFocused {
 			if m.visualMode {
 				// Delete selected sessions
@@ -366,17 +505,18 @@ func (m MainModel) handleSessionListKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 					// Check if session is attached and confirm deletion
 					if session.Attached {
 						m.deleteTarget = session.Name
+						m.deleteSessions = []tmux.Session{session}
 						m.state = confirmDeleteView
 						return m, nil
 					}
 
 					// Delete non-attached session directly
-					err := tmux.KillSession(session.Name)
+					err := m.client(session).KillSession(session.Name)
 					if err != nil {
 						m.error = fmt.Sprintf("Failed to kill session: %v", err)
 					} else {
 						m.success = fmt.Sprintf("Killed session: %s", session.Name)
-						return m, loadSessions
+						return m, loadSessions(m.servers)
 					}
 				}
 			}
@@ -386,34 +526,36 @@ func (m MainModel) handleSessionListKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// Handle other list navigation when not in filter mode and not in visual mode
 	if !m.inputFocused && !m.visualMode {
 		m.list, cmd = m.list.Update(msg)
+		return m, tea.Batch(cmd, m.refreshPreview())
 	}
 
 	return m, cmd
 }
 
 func (m MainModel) handleCreateModeKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "esc", "q", "h":
+	switch {
+	case key.Matches(msg, m.keys.MenuBack):
 		m.state = sessionListView
 		return m.updateSessionList(), nil
 
-	case "enter", "l":
+	case key.Matches(msg, m.keys.Select):
 		selectedIdx := m.list.Index()
 		if selectedIdx == 0 {
-			m.state = loadingView
-			return m, loadRepositories(m.cfg.RepoDirectories)
+			return m.enterRepoList()
 		} else if selectedIdx == 1 {
 			m.state = manualCreateView
 			m.nameInput.SetValue("")
 			m.nameInput.Placeholder = "Session name"
 			m.nameInput.Focus()
 			return m, nil
+		} else if selectedIdx == 2 {
+			return m.enterRestore()
 		}
 
-	case "j", "down":
+	case key.Matches(msg, m.keys.Down):
 		m.list.CursorDown()
 
-	case "k", "up":
+	case key.Matches(msg, m.keys.Up):
 		m.list.CursorUp()
 	}
 
@@ -425,16 +567,16 @@ func (m MainModel) handleRepoListKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	// Handle input when in filter mode first
 	if m.inputFocused {
-		switch msg.String() {
-		case "esc":
+		switch {
+		case key.Matches(msg, m.keys.Cancel):
 			m.inputFocused = false
 			m.nameInput.Blur()
 			m.repoFilterQuery = ""
-			m.filteredRepos = m.repos
+			m = m.filterRepos()
 			return m.updateRepoList(), nil
-		case "enter":
+		case key.Matches(msg, m.keys.Submit):
 			m.repoFilterQuery = m.nameInput.Value()
-			m.filteredRepos = m.fuzzyFilterRepos(m.repoFilterQuery)
+			m = m.filterRepos()
 			m.inputFocused = false
 			m.nameInput.Blur()
 			return m.updateRepoList(), nil
@@ -442,41 +584,67 @@ func (m MainModel) handleRepoListKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			// Update input and apply real-time filtering
 			m.nameInput, cmd = m.nameInput.Update(msg)
 			m.repoFilterQuery = m.nameInput.Value()
-			m.filteredRepos = m.fuzzyFilterRepos(m.repoFilterQuery)
+			m = m.filterRepos()
 			return m.updateRepoList(), cmd
 		}
 	}
 
 	// Handle normal navigation and commands when not in filter mode
-	switch msg.String() {
-	case "esc", "q":
+	switch {
+	case key.Matches(msg, m.keys.Back):
 		m.state = sessionListView
 		return m.updateSessionList(), nil
 
-	case "/":
+	case key.Matches(msg, m.keys.Filter):
 		// Enter filter mode
 		m.inputFocused = true
 		m.nameInput.Focus()
 		m.nameInput.SetValue(m.repoFilterQuery)
 		return m, nil
 
-	case "enter", "l":
+	case key.Matches(msg, m.keys.Select):
 		if len(m.filteredRepos) > 0 {
 			selectedIdx := m.list.Index()
 			if selectedIdx >= 0 && selectedIdx < len(m.filteredRepos) {
 				m.selectedRepo = &m.filteredRepos[selectedIdx]
 				m.state = templateSelectView
+				m = m.loadProjectTemplate(m.selectedRepo.Path)
 				return m.updateTemplateList(), nil
 			}
 		}
 
-	case "j", "down":
+	case key.Matches(msg, m.keys.Sort):
+		m.repoSort = nextSort(repoSorts, m.repoSort)
+		m = m.filterRepos()
+		m.list.Select(0)
+		return m.updateRepoList(), nil
+
+	case key.Matches(msg, m.keys.DirtyOnly):
+		m.dirtyOnly = !m.dirtyOnly
+		m = m.filterRepos()
+		m.list.Select(0)
+		return m.updateRepoList(), nil
+
+	case key.Matches(msg, m.keys.Rescan):
+		if m.scanning {
+			return m, nil
+		}
+		m.scanning, m.rescanning = true, true
+		return m.updateRepoList(), m.scanRepositories()
+
+	case key.Matches(msg, m.keys.AddWorktree):
+		return m.enterAddWorktree()
+
+	case key.Matches(msg, m.keys.RemoveWorktree):
+		return m.enterRemoveWorktree()
+
+	case key.Matches(msg, m.keys.Down):
 		if !m.inputFocused {
 			m.list.CursorDown()
 		}
 		return m, nil
 
-	case "k", "up":
+	case key.Matches(msg, m.keys.Up):
 		if !m.inputFocused {
 			m.list.CursorUp()
 		}
@@ -492,12 +660,12 @@ func (m MainModel) handleRepoListKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m MainModel) handleManualCreateKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "esc":
+	switch {
+	case key.Matches(msg, m.keys.Cancel):
 		m.state = createModeView
 		return m.updateCreateModeList(), nil
 
-	case "enter":
+	case key.Matches(msg, m.keys.Submit):
 		name := strings.TrimSpace(m.nameInput.Value())
 		if name != "" {
 			m.sessionName = name
@@ -514,13 +682,13 @@ func (m MainModel) handleManualCreateKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m MainModel) handleManualDirectoryKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "esc":
+	switch {
+	case key.Matches(msg, m.keys.Cancel):
 		m.state = manualCreateView
 		m.nameInput.Focus()
 		return m, nil
 
-	case "enter":
+	case key.Matches(msg, m.keys.Submit):
 		path := strings.TrimSpace(m.pathInput.Value())
 		if path != "" {
 			// Expand ~ to home directory
@@ -537,6 +705,7 @@ func (m MainModel) handleManualDirectoryKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd
 
 			m.sessionPath = path
 			m.state = templateSelectView
+			m = m.loadProjectTemplate(path)
 			return m.updateTemplateList(), nil
 		}
 	}
@@ -547,8 +716,8 @@ func (m MainModel) handleManualDirectoryKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd
 }
 
 func (m MainModel) handleTemplateSelectKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "esc", "q", "h":
+	switch {
+	case key.Matches(msg, m.keys.MenuBack):
 		if m.selectedRepo != nil {
 			m.state = repoListView
 			return m.updateRepoList(), nil
@@ -558,43 +727,97 @@ func (m MainModel) handleTemplateSelectKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd)
 			return m, nil
 		}
 
-	case "enter", "l":
-		if len(m.templates) > 0 {
+	case key.Matches(msg, m.keys.Select):
+		templates := m.templateChoices()
+		if len(templates) > 0 {
 			selectedIdx := m.list.Index()
-			if selectedIdx >= 0 && selectedIdx < len(m.templates) {
-				template := m.templates[selectedIdx]
+			if selectedIdx >= 0 && selectedIdx < len(templates) {
+				template := templates[selectedIdx]
+				m.paramValues = make(map[string]string)
+				if len(template.Params) > 0 {
+					m.paramTemplate = &template
+					m.paramIndex = 0
+					return m.promptParam(), nil
+				}
 				return m.createSession(&template)
 			}
 		}
 
-	case "j", "down":
+	case key.Matches(msg, m.keys.Down):
 		m.list.CursorDown()
 
-	case "k", "up":
+	case key.Matches(msg, m.keys.Up):
 		m.list.CursorUp()
 	}
 
 	return m, nil
 }
 
+// promptParam asks for the value of the current template parameter,
+// prefilled with its default.
+func (m MainModel) promptParam() MainModel {
+	param := m.paramTemplate.Params[m.paramIndex]
+	m.state = templateParamsView
+	m.nameInput.SetValue(param.Default)
+	m.nameInput.Placeholder = param.Name
+	m.nameInput.CursorEnd()
+	m.nameInput.Focus()
+	return m
+}
+
+func (m MainModel) handleTemplateParamsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Cancel):
+		m.nameInput.Blur()
+		m.nameInput.Placeholder = "Session name"
+		if m.paramIndex > 0 {
+			m.paramIndex--
+			return m.promptParam(), nil
+		}
+		m.state = templateSelectView
+		return m, nil
+
+	case key.Matches(msg, m.keys.Submit):
+		param := m.paramTemplate.Params[m.paramIndex]
+		value := strings.TrimSpace(m.nameInput.Value())
+		// Same rule as muxyard new --param
+		if value == "" && param.Required() {
+			m.error = fmt.Sprintf("Parameter %s has no default and needs a value", param.Name)
+			return m, nil
+		}
+		m.paramValues[param.Name] = value
+		if m.paramIndex+1 < len(m.paramTemplate.Params) {
+			m.paramIndex++
+			return m.promptParam(), nil
+		}
+		m.nameInput.Blur()
+		m.nameInput.Placeholder = "Session name"
+		return m.createSession(m.paramTemplate)
+	}
+
+	var cmd tea.Cmd
+	m.nameInput, cmd = m.nameInput.Update(msg)
+	return m, cmd
+}
+
 func (m MainModel) handleRenameSessionKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "esc":
+	switch {
+	case key.Matches(msg, m.keys.Cancel):
 		m.state = sessionListView
 		m.nameInput.Blur()
 		return m.updateSessionList(), nil
 
-	case "enter":
+	case key.Matches(msg, m.keys.Submit):
 		newName := strings.TrimSpace(m.nameInput.Value())
 		if newName != "" && newName != m.selectedSession.Name {
-			err := tmux.RenameSession(m.selectedSession.Name, newName)
+			err := m.client(*m.selectedSession).RenameSession(m.selectedSession.Name, newName)
 			if err != nil {
 				m.error = fmt.Sprintf("Failed to rename session: %v", err)
 			} else {
 				m.success = fmt.Sprintf("Renamed session to: %s", newName)
 				m.state = sessionListView
 				m.nameInput.Blur()
-				return m, loadSessions
+				return m, loadSessions(m.servers)
 			}
 		} else {
 			m.state = sessionListView
@@ -609,23 +832,22 @@ func (m MainModel) handleRenameSessionKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd)
 }
 
 func (m MainModel) handleConfirmDeleteKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch msg.String() {
-	case "y", "Y":
+	switch {
+	case key.Matches(msg, m.keys.Confirm):
 		// Confirm deletion
-		err := tmux.KillSession(m.deleteTarget)
-		if err != nil {
-			m.error = fmt.Sprintf("Failed to kill session: %v", err)
-		} else {
-			m.success = fmt.Sprintf("Killed session: %s", m.deleteTarget)
-		}
+		m = m.killSessions(m.deleteSessions)
 		m.state = sessionListView
 		m.deleteTarget = ""
-		return m, loadSessions
+		m.deleteSessions = nil
+		m.visualMode = false
+		m.selectedSessions = make(map[int]bool)
+		return m, loadSessions(m.servers)
 
-	case "n", "N", "esc", "q":
+	case key.Matches(msg, m.keys.Deny, m.keys.Back):
 		// Cancel deletion
 		m.state = sessionListView
 		m.deleteTarget = ""
+		m.deleteSessions = nil
 		return m.updateSessionList(), nil
 	}
 
@@ -636,27 +858,32 @@ func (m MainModel) createSession(template *config.SessionTemplate) (tea.Model, t
 	var sessionName, sessionPath string
 
 	if m.selectedRepo != nil {
-		sessionName = tmux.GenerateSessionName(m.selectedRepo.Path, m.sessions)
+		sessionName = tmux.UniqueSessionName(m.selectedRepo.SessionName(), m.sessions)
 		sessionPath = m.selectedRepo.Path
 	} else {
 		sessionName = m.sessionName
 		sessionPath = m.sessionPath
 	}
 
-	err := tmux.CreateSession(sessionName, sessionPath, template)
+	branch, _ := git.CurrentBranch(sessionPath)
+	vars := template.Vars(config.BuiltinVars(sessionName, sessionPath, branch), m.paramValues)
+	template = template.Expand(vars)
+
+	err := m.servers[0].CreateSession(sessionName, sessionPath, template)
 	if err != nil {
 		m.error = fmt.Sprintf("Failed to create session: %v", err)
 		return m, nil
 	}
+	visit := m.recordVisit(m.servers[0].Server, sessionName, sessionPath)
 
-	err = tmux.AttachToSession(sessionName)
+	err = m.servers[0].AttachToSession(sessionName)
 	if err != nil {
 		m.error = fmt.Sprintf("Failed to attach to session: %v", err)
-		return m, nil
+		return m, visit
 	}
 
 	m.quitting = true
-	return m, tea.Quit
+	return m, tea.Sequence(visit, tea.Quit)
 }
 
 func (m MainModel) fuzzyFilterSessions(query string) []tmux.Session {
@@ -670,8 +897,15 @@ func (m MainModel) fuzzyFilterSessions(query string) []tmux.Session {
 		sessionNames[i] = session.Name
 	}
 
-	// Perform fuzzy search
+	// Perform fuzzy search, ranking the sessions used most higher
 	matches := fuzzy.Find(query, sessionNames)
+	now := time.Now()
+	slices.SortStableFunc(matches, func(a, b fuzzy.Match) int {
+		score := func(match fuzzy.Match) float64 {
+			return blend(match.Score, m.frecency.SessionScore(m.sessions[match.Index].Server, match.Str, now))
+		}
+		return cmp.Compare(score(b), score(a))
+	})
 
 	// Return sessions that match
 	filtered := make([]tmux.Session, 0, len(matches))
@@ -694,8 +928,12 @@ func (m MainModel) fuzzyFilterRepos(query string) []git.Repository {
 		repoSearchTerms[i] = repo.Name + " " + repo.Path
 	}
 
-	// Perform fuzzy search
+	// Perform fuzzy search, ranking the repos used most higher
 	matches := fuzzy.Find(query, repoSearchTerms)
+	now := time.Now()
+	slices.SortStableFunc(matches, func(a, b fuzzy.Match) int {
+		return cmp.Compare(blend(b.Score, m.repoFrecency(m.repos[b.Index], now)), blend(a.Score, m.repoFrecency(m.repos[a.Index], now)))
+	})
 
 	// Return repos that match
 	filtered := make([]git.Repository, 0, len(matches))
@@ -758,7 +996,7 @@ func (m *MainModel) updateVisualSelection() {
 }
 
 func (m MainModel) deleteSelectedSessions() (tea.Model, tea.Cmd) {
-	var sessionsToDelete []string
+	var sessionsToDelete []tmux.Session
 	var attachedSessions []string
 
 	// Collect sessions to delete
@@ -767,43 +1005,55 @@ func (m MainModel) deleteSelectedSessions() (tea.Model, tea.Cmd) {
 			session := m.filteredSessions[idx]
 			if session.Attached {
 				attachedSessions = append(attachedSessions, session.Name)
-			} else {
-				sessionsToDelete = append(sessionsToDelete, session.Name)
 			}
+			sessionsToDelete = append(sessionsToDelete, session)
 		}
 	}
 
 	// If there are attached sessions, show confirmation
 	if len(attachedSessions) > 0 {
 		m.deleteTarget = strings.Join(attachedSessions, ", ")
+		m.deleteSessions = sessionsToDelete
 		m.state = confirmDeleteView
-		// Store non-attached sessions for deletion after confirmation
 		return m, nil
 	}
 
-	// Delete non-attached sessions
+	m = m.killSessions(sessionsToDelete)
+
+	// Exit visual mode
+	m.visualMode = false
+	m.selectedSessions = make(map[int]bool)
+
+	return m, loadSessions(m.servers)
+}
+
+func (m MainModel) killSessions(sessions []tmux.Session) MainModel {
+	if len(sessions) == 1 {
+		if err := m.client(sessions[0]).KillSession(sessions[0].Name); err != nil {
+			m.error = fmt.Sprintf("Failed to kill session: %v", err)
+		} else {
+			m.success = fmt.Sprintf("Killed session: %s", sessions[0].Name)
+		}
+		return m
+	}
+
 	var errors []string
-	for _, sessionName := range sessionsToDelete {
-		err := tmux.KillSession(sessionName)
-		if err != nil {
-			errors = append(errors, sessionName)
+	for _, session := range sessions {
+		if err := m.client(session).KillSession(session.Name); err != nil {
+			errors = append(errors, session.Name)
 		}
 	}
 
 	if len(errors) > 0 {
 		m.error = fmt.Sprintf("Failed to kill sessions: %s", strings.Join(errors, ", "))
 	} else {
-		m.success = fmt.Sprintf("Killed %d sessions", len(sessionsToDelete))
+		m.success = fmt.Sprintf("Killed %d sessions", len(sessions))
 	}
-
-	// Exit visual mode
-	m.visualMode = false
-	m.selectedSessions = make(map[int]bool)
-
-	return m, loadSessions
+	return m
 }
 
 func (m MainModel) updateSessionList() MainModel {
+	now := time.Now()
 	items := make([]list.Item, len(m.filteredSessions))
 	for i, session := range m.filteredSessions {
 		status := "detached"
@@ -822,6 +1072,12 @@ func (m MainModel) updateSessionList() MainModel {
 		}
 
 		desc := fmt.Sprintf("%d windows, %s", session.Windows, status)
+		if !session.Activity.IsZero() {
+			desc += " • active " + ago(session.Activity, now)
+		}
+		if len(m.servers) > 1 {
+			desc = fmt.Sprintf("[%s] %s", session.Server, desc)
+		}
 		if m.visualMode && m.selectedSessions[i] {
 			desc = "✓ " + desc
 		}
@@ -839,6 +1095,14 @@ func (m MainModel) updateSessionList() MainModel {
 		selectedCount := len(m.selectedSessions)
 		listTitle = fmt.Sprintf("Tmux Sessions (Visual: %d selected)", selectedCount)
 	}
+	switch m.sessionSort {
+	case sortByName:
+		listTitle += " (by name)"
+	case sortByActivity:
+		listTitle += " (recently active first)"
+	case sortByWindows:
+		listTitle += " (most windows first)"
+	}
 	m.list.Title = listTitle
 	return m
 }
@@ -847,6 +1111,7 @@ func (m MainModel) updateCreateModeList() MainModel {
 	items := []list.Item{
 		listItem{title: "From Git Repository", desc: "Select from configured repo directories"},
 		listItem{title: "Manual Setup", desc: "Enter custom name and directory"},
+		listItem{title: "Restore Saved Session", desc: "Recreate a session saved with 'muxyard save'"},
 	}
 	m.list.SetItems(items)
 	m.list.Title = "Create New Session"
@@ -855,6 +1120,8 @@ func (m MainModel) updateCreateModeList() MainModel {
 
 func (m MainModel) updateRepoList() MainModel {
 	m.state = repoListView
+	now := time.Now()
+
 	items := make([]list.Item, len(m.filteredRepos))
 	for i, repo := range m.filteredRepos {
 		title := repo.Name
@@ -864,6 +1131,26 @@ func (m MainModel) updateRepoList() MainModel {
 			title = m.highlightMatches(repo.Name, m.repoFilterQuery)
 			desc = m.highlightMatches(repo.Path, m.repoFilterQuery)
 		}
+		switch repo.Kind {
+		case git.Worktree:
+			title += " [" + repo.Branch + "]"
+			// Worktrees are shown under their main repository for as long
+			// as the order keeps them together
+			if i > 0 && (m.filteredRepos[i-1].Path == repo.MainPath || m.filteredRepos[i-1].MainPath == repo.MainPath) {
+				title = "└ " + title
+			}
+		case git.Bare:
+			title += " (bare)"
+		}
+		if !repo.IsGit() {
+			title += " (" + badge(repo) + ")"
+		}
+		if status := m.describeStatus(repo, now); status != "" {
+			desc += " • " + status
+		}
+		if repo.Score > 0 {
+			desc += fmt.Sprintf(" • zoxide %.1f", repo.Score)
+		}
 
 		items[i] = listItem{
 			title: title,
@@ -873,23 +1160,64 @@ func (m MainModel) updateRepoList() MainModel {
 	}
 	m.list.SetItems(items)
 	m.list.Title = "Select Repository"
+	switch m.repoSort {
+	case sortByName:
+		m.list.Title += " (by name)"
+	case sortByActivity:
+		m.list.Title += " (recently committed first)"
+	}
+	if m.dirtyOnly {
+		m.list.Title += " (only dirty)"
+	}
+	if m.scanning {
+		m.list.Title += " (scanning…)"
+	}
 	return m
 }
 
 func (m MainModel) updateTemplateList() MainModel {
-	items := make([]list.Item, len(m.templates))
-	for i, template := range m.templates {
+	templates := m.templateChoices()
+	items := make([]list.Item, len(templates))
+	for i, template := range templates {
+		desc := template.Description
+		if template.Source != "" {
+			desc = strings.TrimSpace(desc + " (from " + template.Source + ")")
+		}
 		items[i] = listItem{
 			title: template.Name,
-			desc:  template.Description,
+			desc:  desc,
 			data:  template,
 		}
 	}
 	m.list.SetItems(items)
+	m.list.Select(0)
 	m.list.Title = "Select Template"
 	return m
 }
 
+// loadProjectTemplate looks for a project template in the chosen directory,
+// which is then offered ahead of the configured templates. Its problems
+// show in the config warning.
+func (m MainModel) loadProjectTemplate(dir string) MainModel {
+	template, issues, err := m.cfg.ProjectTemplate(dir)
+	if err != nil {
+		m.error = fmt.Sprintf("Ignoring invalid project template: %v", err)
+	}
+	if len(issues) > 0 {
+		m.configIssues = issues
+		m = m.resize()
+	}
+	m.projectTemplate = template
+	return m
+}
+
+func (m MainModel) templateChoices() []config.SessionTemplate {
+	if m.projectTemplate == nil {
+		return m.templates
+	}
+	return append([]config.SessionTemplate{*m.projectTemplate}, m.templates...)
+}
+
 func getDefaultPath() string {
 	if wd, err := os.Getwd(); err == nil {
 		return wd
@@ -909,12 +1237,21 @@ func (m MainModel) View() string {
 
 	switch m.state {
 	case sessionListView:
-		content = m.list.View()
+		if m.previewVisible() {
+			sessionList := m.list
+			sessionList.SetWidth(m.width - m.previewWidth() - 4)
+			content = m.withPreview(sessionList.View())
+		} else {
+			content = m.list.View()
+		}
 
 		if m.inputFocused {
 			content += "\n\n" + m.styles.FilterBorder.Render("Filter: "+m.nameInput.View())
 		}
 
+		for _, serverError := range m.serverErrors {
+			content += "\n" + m.styles.Error.Render(serverError)
+		}
 		if m.error != "" {
 			content += "\n" + m.styles.Error.Render("Error: "+m.error)
 		}
@@ -922,17 +1259,23 @@ func (m MainModel) View() string {
 			content += "\n" + m.styles.Success.Render(m.success)
 		}
 
-		helpText := "\n'c' create • 'r' rename • 'd/x' delete • '/' filter • 'enter/l' attach • 'ctrl+v' visual • 'q' quit"
+		helpText := helpLine(hint(m.keys.Create, "create"), hint(m.keys.Rename, "rename"), hint(m.keys.Delete, "delete"),
+			hint(m.keys.Filter, "filter"), hint(m.keys.Attach, "attach"), hint(m.keys.Tree, "tree"),
+			hint(m.keys.Visual, "visual"), hint(m.keys.Preview, "preview"), hint(m.keys.Sort, "sort"), hint(m.keys.Quit, "quit"))
 		if m.inputFocused {
-			helpText = "\n'enter' apply filter • 'esc' cancel filter"
+			helpText = helpLine(hint(m.keys.Submit, "apply filter"), hint(m.keys.Cancel, "cancel filter"))
 		} else if m.visualMode {
-			helpText = "\n'j/k' select • 'd/x' delete selected • 'esc/ctrl+v' exit visual • 'q' quit"
+			helpText = helpLine(m.keys.navHint("select"), hint(m.keys.Delete, "delete selected"),
+				hint(either(m.keys.Cancel, m.keys.Visual), "exit visual"), hint(m.keys.Quit, "quit"))
 		}
 		content += m.styles.Help.Render(helpText)
 
 	case createModeView:
 		content = m.list.View()
-		content += m.styles.Help.Render("\n'enter/l' select • 'j/k' navigate • 'h/esc' back")
+		if m.error != "" {
+			content += "\n" + m.styles.Error.Render("Error: "+m.error)
+		}
+		content += m.styles.Help.Render(helpLine(hint(m.keys.Select, "select"), m.keys.navHint("navigate"), hint(m.keys.MenuBack, "back")))
 
 	case repoListView:
 		content = m.list.View()
@@ -948,39 +1291,118 @@ func (m MainModel) View() string {
 			content += "\n" + m.styles.Success.Render(m.success)
 		}
 
-		helpText := "\n'enter/l' select • 'j/k' navigate • '/' filter • 'h/esc' back"
+		helpText := helpLine(hint(m.keys.Select, "select"), m.keys.navHint("navigate"), hint(m.keys.Filter, "filter"),
+			hint(m.keys.Sort, "sort"), hint(m.keys.DirtyOnly, "only dirty"), hint(m.keys.Rescan, "rescan"),
+			hint(m.keys.AddWorktree, "new worktree"), hint(m.keys.RemoveWorktree, "remove worktree"), hint(m.keys.Back, "back"))
 		if m.inputFocused {
-			helpText = "\n'enter' apply filter • 'esc' cancel filter"
+			helpText = helpLine(hint(m.keys.Submit, "apply filter"), hint(m.keys.Cancel, "cancel filter"))
 		}
 		content += m.styles.Help.Render(helpText)
 
 	case manualCreateView:
 		content = "Enter session name:\n\n"
 		content += m.styles.Input.Render(m.nameInput.View())
-		content += m.styles.Help.Render("\n'enter' continue • 'esc' back")
+		content += m.styles.Help.Render(helpLine(hint(m.keys.Submit, "continue"), hint(m.keys.Cancel, "back")))
 
 	case manualDirectoryView:
 		content = fmt.Sprintf("Session: %s\n\nEnter directory path:\n\n", m.sessionName)
 		content += m.styles.Input.Render(m.pathInput.View())
-		content += m.styles.Help.Render("\n'enter' continue • 'esc' back")
+		content += m.styles.Help.Render(helpLine(hint(m.keys.Submit, "continue"), hint(m.keys.Cancel, "back")))
 
 	case templateSelectView:
 		content = m.list.View()
-		content += m.styles.Help.Render("\n'enter/l' create session • 'j/k' navigate • 'h/esc' back")
+		if m.error != "" {
+			content += "\n" + m.styles.Error.Render("Error: "+m.error)
+		}
+		content += m.styles.Help.Render(helpLine(hint(m.keys.Select, "create session"), m.keys.navHint("navigate"), hint(m.keys.MenuBack, "back")))
+
+	case templateParamsView:
+		param := m.paramTemplate.Params[m.paramIndex]
+		content = fmt.Sprintf("Template: %s (%d/%d)\n\n", m.paramTemplate.Name, m.paramIndex+1, len(m.paramTemplate.Params))
+		if param.Description != "" {
+			content += fmt.Sprintf("%s (%s):\n\n", param.Description, param.Name)
+		} else {
+			content += fmt.Sprintf("Enter %s:\n\n", param.Name)
+		}
+		content += m.styles.Input.Render(m.nameInput.View())
+		if m.error != "" {
+			content += "\n" + m.styles.Error.Render("Error: "+m.error)
+		}
+		content += m.styles.Help.Render(helpLine(hint(m.keys.Submit, "continue"), hint(m.keys.Cancel, "back")))
 
 	case renameSessionView:
 		content = fmt.Sprintf("Rename session: %s\n\n", m.selectedSession.Name)
 		content += m.styles.Input.Render(m.nameInput.View())
-		content += m.styles.Help.Render("\n'enter' rename • 'esc' cancel")
+		content += m.styles.Help.Render(helpLine(hint(m.keys.Submit, "rename"), hint(m.keys.Cancel, "cancel")))
+
+	case treeView:
+		content = m.list.View()
+		if m.error != "" {
+			content += "\n" + m.styles.Error.Render("Error: "+m.error)
+		}
+		content += m.styles.Help.Render(helpLine(hint(without(m.keys.Attach, m.keys.Expand), "jump"), hint(m.keys.Expand, "expand"),
+			hint(m.keys.Collapse, "collapse"), m.keys.navHint("navigate"), hint(without(m.keys.Back, m.keys.Collapse), "back")))
+
+	case restoreListView:
+		content = m.list.View()
+		if m.error != "" {
+			content += "\n" + m.styles.Error.Render("Error: "+m.error)
+		}
+		content += m.styles.Help.Render(helpLine(hint(m.keys.Select, "restore and attach"), m.keys.navHint("navigate"), hint(m.keys.MenuBack, "back")))
 
 	case loadingView:
 		content = fmt.Sprintf("\n%s Loading repositories...\n", m.spinner.View())
 
+	case worktreeBranchView:
+		content = fmt.Sprintf("New worktree of %s\n\nBranch (new or existing):\n\n", filepath.Base(m.worktreeMain))
+		content += m.styles.Input.Render(m.nameInput.View())
+		if m.error != "" {
+			content += "\n" + m.styles.Error.Render("Error: "+m.error)
+		}
+		content += m.styles.Help.Render(helpLine(hint(m.keys.Submit, "add worktree"), hint(m.keys.Cancel, "back")))
+
+	case confirmRemoveWorktreeView:
+		content = fmt.Sprintf("Remove worktree: %s?\n\n", m.removeTarget.Path)
+		content += fmt.Sprintf("The branch %s is kept", m.removeTarget.Branch)
+		if sessions := m.worktreeSessions(*m.removeTarget); len(sessions) > 0 {
+			content += fmt.Sprintf(" and the session %s is killed", sessions[0].Name)
+		}
+		content += ".\n\n"
+		content += m.styles.Help.Render(strings.TrimPrefix(helpLine(hint(m.keys.Confirm, "yes"), hint(either(m.keys.Deny, m.keys.Back), "no")), "\n"))
+
 	case confirmDeleteView:
 		content = fmt.Sprintf("Delete attached session: %s?\n\n", m.deleteTarget)
 		content += "This session is currently attached and deleting it will close all windows.\n\n"
-		content += m.styles.Help.Render("'y' yes • 'n/esc' no")
+		content += m.styles.Help.Render(strings.TrimPrefix(helpLine(hint(m.keys.Confirm, "yes"), hint(either(m.keys.Deny, m.keys.Back), "no")), "\n"))
+	}
+
+	return m.styles.Title.Render("Muxyard - Tmux Session Manager") + "\n\n" + m.configWarning() + content
+}
+
+// resize fits the list into the window below the title and any warning.
+func (m MainModel) resize() MainModel {
+	m.list.SetSize(m.width-4, m.height-8-lipgloss.Height(m.configWarning()))
+	return m
+}
+
+// maxConfigIssues is how many config issues the warning banner lists.
+const maxConfigIssues = 3
+
+// configWarning renders the problems found in the config file, if any.
+func (m MainModel) configWarning() string {
+	if len(m.configIssues) == 0 {
+		return ""
+	}
+
+	lines := []string{m.styles.Error.Render(fmt.Sprintf("Config problems (%d):", len(m.configIssues)))}
+	for i, issue := range m.configIssues {
+		if i == maxConfigIssues {
+			lines = append(lines, fmt.Sprintf("  ...and %d more", len(m.configIssues)-maxConfigIssues))
+			break
+		}
+		lines = append(lines, ansi.Truncate("  "+issue.String(), max(m.width, 20), "…"))
 	}
+	lines = append(lines, m.styles.Dimmed.Render("Run 'muxyard config validate' for details; press any key to dismiss"))
 
-	return m.styles.Title.Render("Muxyard - Tmux Session Manager") + "\n\n" + content
+	return strings.Join(lines, "\n") + "\n\n"
 }
@@ -0,0 +1,882 @@
+package ui
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"muxyard/internal/config"
+	"muxyard/internal/frecency"
+	"muxyard/internal/git"
+	"muxyard/internal/tmux"
+	"muxyard/internal/zoxide"
+)
+
+func newTestModel(t *testing.T, sessions ...tmux.Session) (MainModel, *tmux.FakeRunner) {
+	t.Helper()
+	t.Setenv("TMUX", "")
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	runner := tmux.NewFakeRunner()
+	m := NewMainModel(config.DefaultConfig(), []*tmux.Client{tmux.NewClient(runner)})
+	m = update(t, m, tea.WindowSizeMsg{Width: 100, Height: 40})
+	m = update(t, m, sessionsLoadedMsg{sessions: sessions})
+	return m, runner
+}
+
+func update(t *testing.T, m MainModel, msg tea.Msg) MainModel {
+	t.Helper()
+	model, _ := m.Update(msg)
+	return model.(MainModel)
+}
+
+func press(t *testing.T, m MainModel, keys ...string) MainModel {
+	t.Helper()
+	for _, k := range keys {
+		var msg tea.KeyMsg
+		switch k {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "esc":
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		case "ctrl+u":
+			msg = tea.KeyMsg{Type: tea.KeyCtrlU}
+		case "ctrl+r":
+			msg = tea.KeyMsg{Type: tea.KeyCtrlR}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		}
+		m = update(t, m, msg)
+	}
+	return m
+}
+
+func assertCommands(t *testing.T, runner *tmux.FakeRunner, expected ...string) {
+	t.Helper()
+	if len(expected) == 0 && len(runner.Calls) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(runner.Commands(), expected) {
+		t.Errorf("tmux ran\n%s\nwant\n%s", strings.Join(runner.Commands(), "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestRenameSession(t *testing.T) {
+	m, runner := newTestModel(t, tmux.Session{Name: "api", Windows: 2})
+
+	m = press(t, m, "r", "ctrl+u", "backend", "enter")
+
+	assertCommands(t, runner, "rename-session -t api backend")
+	if m.state != sessionListView {
+		t.Errorf("Expected to return to the session list, got state %d", m.state)
+	}
+}
+
+func TestKillDetachedSession(t *testing.T) {
+	m, runner := newTestModel(t,
+		tmux.Session{Name: "api", Windows: 2},
+		tmux.Session{Name: "web", Windows: 1},
+	)
+
+	press(t, m, "j", "d")
+
+	assertCommands(t, runner, "kill-session -t web")
+}
+
+func TestKillAttachedSessionNeedsConfirmation(t *testing.T) {
+	m, runner := newTestModel(t, tmux.Session{Name: "api", Windows: 2, Attached: true})
+
+	m = press(t, m, "d")
+	if m.state != confirmDeleteView {
+		t.Fatalf("Expected delete confirmation, got state %d", m.state)
+	}
+	assertCommands(t, runner)
+
+	press(t, m, "y")
+	assertCommands(t, runner, "kill-session -t api")
+}
+
+func TestCreateManualSession(t *testing.T) {
+	dir := t.TempDir()
+	m, runner := newTestModel(t)
+
+	m = press(t, m, "c", "j", "enter", "scratch", "enter")
+	if m.state != manualDirectoryView {
+		t.Fatalf("Expected directory prompt, got state %d", m.state)
+	}
+
+	m = press(t, m, "ctrl+u", dir, "enter")
+	if m.state != templateSelectView {
+		t.Fatalf("Expected template selection, got state %d", m.state)
+	}
+
+	// Default templates are basic, coding, monitor; the list keeps the
+	// cursor from the previous menu, so go to the top before picking coding.
+	m = press(t, m, "k", "k", "j", "enter")
+
+	assertCommands(t, runner,
+		"new-session -d -s scratch -P -F #{window_id} #{pane_id} -c "+dir+" -n editor sh -c nvim .; exec $SHELL",
+		"new-window -t scratch -P -F #{window_id} #{pane_id} -c "+dir+" -n server",
+		"new-window -t scratch -P -F #{window_id} #{pane_id} -c "+dir+" -n shell",
+		"select-window -t scratch:editor",
+		"attach-session -t scratch",
+	)
+	if !m.quitting {
+		t.Error("Expected the TUI to quit after attaching")
+	}
+}
+
+func TestKillSessionOnOtherServer(t *testing.T) {
+	t.Setenv("TMUX", "")
+
+	work := tmux.NewFakeRunner()
+	scratch := tmux.NewFakeRunner()
+	servers := []*tmux.Client{
+		tmux.NewServerClient("work", work),
+		tmux.NewServerClient("scratch", scratch),
+	}
+
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	m := NewMainModel(config.DefaultConfig(), servers)
+	m = update(t, m, tea.WindowSizeMsg{Width: 100, Height: 40})
+	m = update(t, m, sessionsLoadedMsg{sessions: []tmux.Session{
+		{Name: "api", Windows: 1, Server: "work"},
+		{Name: "api", Windows: 1, Server: "scratch"},
+	}})
+
+	press(t, m, "j", "d")
+
+	assertCommands(t, work)
+	assertCommands(t, scratch, "kill-session -t api")
+}
+
+func TestPreviewFollowsCursor(t *testing.T) {
+	m, runner := newTestModel(t,
+		tmux.Session{Name: "api", Windows: 2},
+		tmux.Session{Name: "web", Windows: 1},
+	)
+	m = update(t, m, tea.WindowSizeMsg{Width: 120, Height: 40})
+	runner.Respond("list-windows", "0\teditor\t2\t1\tc5a1,80x24,0,0,1\n", nil)
+	runner.Respond("capture-pane", "$ make test\nok\n", nil)
+
+	model, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")})
+	if cmd == nil {
+		t.Fatal("Expected moving the cursor to load a preview")
+	}
+	m = update(t, model.(MainModel), cmd())
+
+	assertCommands(t, runner,
+		"list-windows -t web -F #{window_index}\t#{window_name}\t#{window_panes}\t#{window_active}\t#{window_layout}",
+		"capture-pane -p -e -t web",
+	)
+	if view := m.View(); !strings.Contains(view, "make test") || !strings.Contains(view, "0: editor (2 panes)") {
+		t.Errorf("Expected the preview in the view, got:\n%s", view)
+	}
+}
+
+func TestTreeJumpToPane(t *testing.T) {
+	m, runner := newTestModel(t, tmux.Session{Name: "api", Windows: 2})
+	runner.Respond("list-windows", "0\teditor\t1\t1\tc5a1,80x24,0,0,1\n1\tdev\t2\t0\t9a3d,80x24,0,0[80x12,0,0,2,80x11,0,13,3]\n", nil)
+	runner.Respond("list-panes", "0\t0\tnvim\t/src/api\t1\n1\t0\tzsh\t/src/api\t1\n1\t1\tgo\t/src/api/cmd\t0\n", nil)
+
+	model, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("t")})
+	m = update(t, model.(MainModel), cmd())
+	if m.state != treeView {
+		t.Fatalf("Expected tree view, got state %d", m.state)
+	}
+
+	// session, editor, dev -> expand dev -> its second pane
+	m = press(t, m, "j", "j", "l", "j", "j", "enter")
+
+	if got := runner.Commands()[len(runner.Calls)-1]; got != "attach-session -t api:1.1" {
+		t.Errorf("Expected to jump to pane api:1.1, got %q", got)
+	}
+	if !m.quitting {
+		t.Error("Expected the TUI to quit after jumping")
+	}
+}
+
+func TestCreateSessionPromptsForParams(t *testing.T) {
+	t.Setenv("TMUX", "")
+	dir := t.TempDir()
+
+	cfg := config.DefaultConfig()
+	cfg.Templates = []config.SessionTemplate{{
+		Name:   "service",
+		Params: []config.TemplateParam{{Name: "port", Default: "8080"}, {Name: "env"}},
+		Windows: []config.WindowConfig{
+			{Name: "{{.session}}", Command: "serve --port {{.port}} --env {{.env}}"},
+		},
+	}}
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	runner := tmux.NewFakeRunner()
+	m := NewMainModel(cfg, []*tmux.Client{tmux.NewClient(runner)})
+	m = update(t, m, tea.WindowSizeMsg{Width: 100, Height: 40})
+	m = update(t, m, sessionsLoadedMsg{})
+
+	m = press(t, m, "c", "j", "enter", "svc", "enter", "ctrl+u", dir, "enter", "enter")
+	if m.state != templateParamsView {
+		t.Fatalf("Expected parameter prompt, got state %d", m.state)
+	}
+	if m.nameInput.Value() != "8080" {
+		t.Errorf("Expected the prompt to be prefilled with the default, got %q", m.nameInput.Value())
+	}
+
+	m = press(t, m, "ctrl+u", "9000", "enter", "enter")
+	if m.state != templateParamsView || m.error == "" {
+		t.Fatalf("Expected an empty value to be refused for a parameter without default, got state %d", m.state)
+	}
+	assertCommands(t, runner)
+
+	m = press(t, m, "staging", "enter")
+
+	assertCommands(t, runner,
+		"new-session -d -s svc -P -F #{window_id} #{pane_id} -c "+dir+" -n svc sh -c serve --port 9000 --env staging; exec $SHELL",
+		"attach-session -t svc",
+	)
+}
+
+func TestConfigWarningBanner(t *testing.T) {
+	t.Setenv("TMUX", "")
+
+	cfg := config.DefaultConfig()
+	cfg.Issues = []config.Issue{{File: "config.yaml", Line: 3, Message: `unknown field "focused_windw" in template`}}
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	m := NewMainModel(cfg, []*tmux.Client{tmux.NewClient(tmux.NewFakeRunner())})
+	m = update(t, m, tea.WindowSizeMsg{Width: 100, Height: 40})
+	m = update(t, m, sessionsLoadedMsg{sessions: []tmux.Session{{Name: "api", Windows: 1}}})
+
+	if view := m.View(); !strings.Contains(view, `config.yaml:3: unknown field "focused_windw" in template`) {
+		t.Errorf("Expected the config issue in the view, got:\n%s", view)
+	}
+
+	m = press(t, m, "j")
+	if view := m.View(); strings.Contains(view, "Config problems") {
+		t.Errorf("Expected a key press to dismiss the warning, got:\n%s", view)
+	}
+}
+
+func TestProjectTemplateIssues(t *testing.T) {
+	dir := t.TempDir()
+	project := "windows:\n  - name: server\n    comand: make run\n"
+	if err := os.WriteFile(filepath.Join(dir, config.ProjectFile), []byte(project), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, _ := newTestModel(t)
+	m = press(t, m, "c", "enter")
+	m = update(t, m, reposLoadedMsg{{Name: "api", Path: dir}})
+	m = press(t, m, "enter")
+
+	want := filepath.Join(dir, config.ProjectFile) + `:3: unknown field "comand" in window`
+	if view := m.View(); !strings.Contains(view, want) {
+		t.Errorf("Expected the project file issue in the view, got:\n%s", view)
+	}
+}
+
+func TestConfigReload(t *testing.T) {
+	m, _ := newTestModel(t)
+	m = press(t, m, "c", "j", "enter", "scratch", "enter", "ctrl+u", t.TempDir(), "enter")
+	if m.state != templateSelectView {
+		t.Fatalf("Expected template selection, got state %d", m.state)
+	}
+
+	reloaded := config.DefaultConfig()
+	reloaded.RepoDirectories = []config.RepoDirectory{{Path: "/srv/src"}}
+	reloaded.Templates = []config.SessionTemplate{{Name: "solo", Windows: []config.WindowConfig{{Name: "main"}}}}
+	m = update(t, m, configCheckedMsg{cfg: reloaded})
+
+	if len(m.templates) != 1 || len(m.list.Items()) != 1 || m.cfg.RepoDirectories[0].Path != "/srv/src" {
+		t.Errorf("Expected the reloaded templates and directories, got %+v", m.templates)
+	}
+	if m.success != "Config reloaded" {
+		t.Errorf("Expected a reload message, got %q", m.success)
+	}
+
+	broken := config.DefaultConfig()
+	broken.Issues = []config.Issue{{File: "config.yaml", Line: 4, Message: "template \"x\" has no windows"}}
+	m = update(t, m, configCheckedMsg{cfg: broken})
+
+	if len(m.templates) != 1 {
+		t.Error("Expected a config with problems not to be applied")
+	}
+	if !strings.Contains(m.View(), `Config not reloaded: config.yaml:4: template "x" has no windows`) {
+		t.Errorf("Expected the problem in the status line, got:\n%s", m.View())
+	}
+}
+
+func TestCustomKeys(t *testing.T) {
+	t.Setenv("TMUX", "")
+
+	cfg := config.DefaultConfig()
+	cfg.Keys.Rename = config.KeyList{"R"}
+	cfg.Keys.Delete = config.KeyList{"D"}
+	cfg.Keys.Quit = config.KeyList{"ctrl+c"}
+	cfg.Keys.Preview = config.KeyList{}
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	runner := tmux.NewFakeRunner()
+	m := NewMainModel(cfg, []*tmux.Client{tmux.NewClient(runner)})
+	m = update(t, m, tea.WindowSizeMsg{Width: 100, Height: 40})
+	m = update(t, m, sessionsLoadedMsg{sessions: []tmux.Session{{Name: "api", Windows: 1}, {Name: "web", Windows: 1}}})
+
+	view := m.View()
+	for _, want := range []string{"'R' rename", "'D' delete", "'ctrl+c' quit"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q in the help, got:\n%s", want, view)
+		}
+	}
+	if strings.Contains(view, "'p' preview") {
+		t.Errorf("Expected no hint for the disabled preview, got:\n%s", view)
+	}
+
+	// The old keys do nothing, not even quit
+	m = press(t, m, "d", "x", "r", "q", "p")
+	if m.state != sessionListView || m.quitting {
+		t.Fatalf("Expected the unbound keys to be ignored, got state %d", m.state)
+	}
+	assertCommands(t, runner)
+
+	press(t, m, "j", "D")
+	assertCommands(t, runner, "kill-session -t web")
+}
+
+// The default keys are the ones muxyard always had: 'h' backs out of the
+// menus but not the repository list or a confirmation.
+func TestDefaultBackKeys(t *testing.T) {
+	m, runner := newTestModel(t, tmux.Session{Name: "api", Windows: 2, Attached: true})
+
+	if m = press(t, m, "c", "h"); m.state != sessionListView {
+		t.Errorf("Expected 'h' to leave the create menu, got state %d", m.state)
+	}
+
+	m = press(t, m, "c", "enter")
+	m = update(t, m, reposLoadedMsg{{Name: "web", Path: "/src/web"}})
+	if m = press(t, m, "h"); m.state != repoListView {
+		t.Errorf("Expected 'h' to stay in the repository list, got state %d", m.state)
+	}
+	if m = press(t, m, "q"); m.state != sessionListView {
+		t.Errorf("Expected 'q' to leave the repository list, got state %d", m.state)
+	}
+
+	if m = press(t, m, "d", "h"); m.state != confirmDeleteView {
+		t.Errorf("Expected 'h' to leave the confirmation open, got state %d", m.state)
+	}
+	if m = press(t, m, "q"); m.state != sessionListView {
+		t.Errorf("Expected 'q' to deny the confirmation, got state %d", m.state)
+	}
+	assertCommands(t, runner)
+}
+
+func TestThemes(t *testing.T) {
+	for _, name := range config.Themes {
+		if _, ok := themes[name]; !ok {
+			t.Errorf("Theme %q has no colors", name)
+		}
+	}
+
+	profile, dark := lipgloss.ColorProfile(), lipgloss.HasDarkBackground()
+	t.Cleanup(func() {
+		lipgloss.SetColorProfile(profile)
+		lipgloss.SetHasDarkBackground(dark)
+	})
+	lipgloss.SetColorProfile(termenv.ANSI)
+
+	// 16 color terminals get the hand-picked color of the right variant
+	lipgloss.SetHasDarkBackground(true)
+	if got := NewStyles("catppuccin", config.ColorConfig{}).Selected.Render("x"); got != "\x1b[1;95mx\x1b[0m" {
+		t.Errorf("Expected bright magenta on a dark background, got %q", got)
+	}
+	lipgloss.SetHasDarkBackground(false)
+	if got := NewStyles("catppuccin", config.ColorConfig{}).Selected.Render("x"); got != "\x1b[1;35mx\x1b[0m" {
+		t.Errorf("Expected magenta on a light background, got %q", got)
+	}
+	if got := NewStyles("catppuccin", config.ColorConfig{Selected: "2"}).Selected.Render("x"); got != "\x1b[1;32mx\x1b[0m" {
+		t.Errorf("Expected the configured color to override the theme, got %q", got)
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	styles := NewStyles("catppuccin", config.ColorConfig{Selected: "2"})
+	if got := styles.Selected.Render("x"); got != "\x1b[1mx\x1b[0m" {
+		t.Errorf("Expected bold without color under NO_COLOR, got %q", got)
+	}
+	if got := styles.Title.Render("x"); !strings.Contains(got, "\x1b[1;7mx") {
+		t.Errorf("Expected a reversed title under NO_COLOR, got %q", got)
+	}
+}
+
+func TestCreateSessionFromWorktree(t *testing.T) {
+	dir := t.TempDir()
+	m, runner := newTestModel(t, tmux.Session{Name: "api", Windows: 1})
+
+	m = press(t, m, "c", "enter")
+	m = update(t, m, reposLoadedMsg{
+		{Name: "api", Path: "/src/api", Branch: "main"},
+		{Name: "api-login", Path: dir, Kind: git.Worktree, Branch: "login", MainPath: "/src/api"},
+	})
+	if title := m.list.Items()[1].(listItem).title; title != "└ api-login [login]" {
+		t.Errorf("Expected the worktree grouped under its repository, got %q", title)
+	}
+
+	m = press(t, m, "j", "enter", "enter")
+
+	assertCommands(t, runner,
+		"new-session -d -s api@login -P -F #{window_id} #{pane_id} -c "+dir+" -n main",
+		"attach-session -t api@login",
+	)
+}
+
+func TestAddAndRemoveWorktree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available on this system")
+	}
+	root := t.TempDir()
+	api := filepath.Join(root, "api")
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch", "main", api},
+		{"-C", api, "-c", "user.name=t", "-c", "user.email=t@t", "commit", "--quiet", "--allow-empty", "-m", "init"},
+	} {
+		if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Skipf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	worktree := filepath.Join(root, "api-login")
+
+	m, runner := newTestModel(t)
+	m = press(t, m, "c", "enter")
+	m = update(t, m, reposLoadedMsg{{Name: "api", Path: api, Branch: "main"}})
+	m = press(t, m, "w", "login", "enter")
+	if m.state != templateSelectView {
+		t.Fatalf("Expected template selection, got state %d (error %q)", m.state, m.error)
+	}
+	if !git.IsGitRepository(worktree) {
+		t.Fatalf("Expected a worktree in %s", worktree)
+	}
+
+	m = press(t, m, "enter")
+	assertCommands(t, runner,
+		"new-session -d -s api@login -P -F #{window_id} #{pane_id} -c "+worktree+" -n main",
+		"attach-session -t api@login",
+	)
+
+	// Back in the repo list with the session running, renamed as the name
+	// was taken by an unrelated session
+	m, runner = newTestModel(t,
+		tmux.Session{Name: "api@login", Windows: 1, Path: filepath.Join(root, "other")},
+		tmux.Session{Name: "api@login_2", Windows: 1, Path: worktree},
+	)
+	m = press(t, m, "c", "enter")
+	repo, _ := git.RepositoryAt(worktree)
+	m = update(t, m, reposLoadedMsg{{Name: "api", Path: api, Branch: "main"}, repo})
+
+	if err := os.WriteFile(filepath.Join(worktree, "notes.txt"), []byte("wip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m = press(t, m, "j", "W", "y")
+	if !strings.Contains(m.error, "uncommitted changes") || !git.IsGitRepository(worktree) {
+		t.Errorf("Expected a dirty worktree to be kept, got error %q", m.error)
+	}
+	assertCommands(t, runner)
+
+	if err := os.Remove(filepath.Join(worktree, "notes.txt")); err != nil {
+		t.Fatal(err)
+	}
+	m = press(t, m, "W", "y")
+	if git.IsGitRepository(worktree) || m.success != "Removed worktree: "+worktree+"; killed session: api@login_2" {
+		t.Errorf("Expected the worktree to be removed, got %q (error %q)", m.success, m.error)
+	}
+	assertCommands(t, runner, "kill-session -t api@login_2")
+	if len(m.list.Items()) != 1 {
+		t.Errorf("Expected only the main repository left, got %d items", len(m.list.Items()))
+	}
+}
+
+func TestRepoStatusSortAndFilter(t *testing.T) {
+	m, _ := newTestModel(t)
+	m = press(t, m, "c", "enter")
+	m = update(t, m, reposLoadedMsg{{Name: "api", Path: "/src/api"}, {Name: "docs", Path: "/src/docs"}, {Name: "web", Path: "/src/web"}})
+
+	if desc := m.list.Items()[0].(listItem).desc; desc != "/src/api • reading status…" {
+		t.Errorf("Expected a placeholder until the status arrives, got %q", desc)
+	}
+
+	now := time.Now()
+	m = update(t, m, repoStatusMsg{path: "/src/api", repoStatus: repoStatus{status: git.Status{Branch: "main", LastCommit: now.Add(-72 * time.Hour)}}})
+	m = update(t, m, repoStatusMsg{path: "/src/web", repoStatus: repoStatus{status: git.Status{Branch: "login", Dirty: true, Upstream: true, Ahead: 2, LastCommit: now.Add(-3 * time.Hour)}}})
+	m = update(t, m, repoStatusMsg{path: "/src/docs", repoStatus: repoStatus{err: errors.New("not a git repository")}})
+
+	descriptions := func() []string {
+		var descs []string
+		for _, item := range m.list.Items() {
+			descs = append(descs, item.(listItem).desc)
+		}
+		return descs
+	}
+	want := []string{
+		"/src/api • main • clean • committed 3d ago",
+		"/src/docs • status unavailable",
+		"/src/web • login • dirty • ↑2 ↓0 • committed 3h ago",
+	}
+	if got := descriptions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	m = press(t, m, "s", "s")
+	if got := descriptions(); got[0] != want[2] || got[1] != want[0] {
+		t.Errorf("Expected the most recent commit first, got %q", got)
+	}
+
+	m = press(t, m, "D")
+	if got := descriptions(); len(got) != 1 || got[0] != want[2] {
+		t.Errorf("Expected only the dirty repository, got %q", got)
+	}
+	if !strings.Contains(m.View(), "(only dirty)") {
+		t.Error("Expected the list title to mention the filter")
+	}
+}
+
+func TestCachedRepoList(t *testing.T) {
+	m, _ := newTestModel(t)
+	path, err := git.IndexPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached := []git.Repository{{Name: "api", Path: "/src/api"}, {Name: "docs", Path: "/src/docs"}, {Name: "web", Path: "/src/web"}}
+	if err := git.SaveIndex(path, m.cfg.RepoDirectories, m.cfg.Markers(), cached); err != nil {
+		t.Fatal(err)
+	}
+
+	m = press(t, m, "c", "enter")
+	if m.state != repoListView || len(m.list.Items()) != 3 || !strings.Contains(m.list.Title, "scanning") {
+		t.Fatalf("Expected the cached repositories while scanning, got state %d with %d items", m.state, len(m.list.Items()))
+	}
+
+	// The scan finds docs gone and a new repository, with the cursor staying on web
+	m = press(t, m, "j", "j")
+	m = update(t, m, reposLoadedMsg{{Name: "api", Path: "/src/api"}, {Name: "cli", Path: "/src/cli"}, {Name: "web", Path: "/src/web"}})
+	var names []string
+	for _, item := range m.list.Items() {
+		names = append(names, item.(listItem).data.(git.Repository).Name)
+	}
+	if !reflect.DeepEqual(names, []string{"api", "cli", "web"}) || strings.Contains(m.list.Title, "scanning") {
+		t.Errorf("Expected the scanned repositories, got %q titled %q", names, m.list.Title)
+	}
+	if repo, _ := m.highlightedRepo(); repo.Name != "web" {
+		t.Errorf("Expected the cursor to stay on web, got %q", repo.Name)
+	}
+	if m.success != "Repositories updated: 1 new, 1 gone" {
+		t.Errorf("Expected the changes to be reported, got %q", m.success)
+	}
+
+	m = press(t, m, "ctrl+r")
+	if !m.rescanning || !strings.Contains(m.list.Title, "scanning") {
+		t.Errorf("Expected a rescan to start, got title %q", m.list.Title)
+	}
+	m = update(t, m, reposLoadedMsg{{Name: "api", Path: "/src/api"}, {Name: "cli", Path: "/src/cli"}, {Name: "web", Path: "/src/web"}})
+	if m.success != "Repositories up to date" {
+		t.Errorf("Expected the rescan to be reported, got %q", m.success)
+	}
+}
+
+func TestProjectBadges(t *testing.T) {
+	m, _ := newTestModel(t)
+	m = press(t, m, "c", "enter")
+	m = update(t, m, reposLoadedMsg{
+		{Name: "api", Path: "/src/api", Marker: git.GitMarker},
+		{Name: "notes", Path: "/src/notes", Marker: ".hg"},
+		{Name: "tool", Path: "/src/tool", Marker: "go.mod"},
+	})
+
+	var items []string
+	for _, item := range m.list.Items() {
+		items = append(items, item.(listItem).title+": "+item.(listItem).desc)
+	}
+	want := []string{"api: /src/api • reading status…", "notes (hg): /src/notes", "tool (go): /src/tool"}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(items, "\n"))
+	}
+
+	m = press(t, m, "j", "w")
+	if m.state != repoListView || m.error != "notes is not a git repository" {
+		t.Errorf("Expected no worktree for a Mercurial repository, got state %d (error %q)", m.state, m.error)
+	}
+}
+
+func TestFrecencyOrder(t *testing.T) {
+	now := time.Now()
+	sessions := []tmux.Session{
+		{Name: "apx1", Windows: 1, Activity: now.Add(-time.Hour)},
+		{Name: "apx2", Windows: 3, Activity: now.Add(-2 * time.Hour)},
+		{Name: "docs", Windows: 2, Activity: now.Add(-time.Minute)},
+	}
+	m, runner := newTestModel(t)
+	m.frecency.Visit("", "docs", "", now)
+	m.frecency.Visit("", "apx2", "", now.Add(-30*24*time.Hour))
+	m = update(t, m, sessionsLoadedMsg{sessions: sessions})
+
+	names := func() []string {
+		var names []string
+		for _, item := range m.list.Items() {
+			names = append(names, item.(listItem).data.(tmux.Session).Name)
+		}
+		return names
+	}
+	for _, want := range [][]string{
+		{"docs", "apx2", "apx1"}, // by frecency
+		{"apx1", "apx2", "docs"}, // by name
+		{"docs", "apx1", "apx2"}, // recently active first
+		{"apx2", "docs", "apx1"}, // most windows first
+		{"docs", "apx2", "apx1"}, // and back
+	} {
+		if got := names(); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %q in %q, got %q", want, m.list.Title, got)
+		}
+		m = press(t, m, "s")
+	}
+
+	// Equally good matches are ranked by frecency
+	m = press(t, m, "/", "apx", "enter")
+	if got := names(); !reflect.DeepEqual(got, []string{"apx2", "apx1"}) {
+		t.Errorf("Expected the used session first among equal matches, got %q", got)
+	}
+
+	m = press(t, m, "j")
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	runCmd(cmd)
+	assertCommands(t, runner, "attach-session -t apx1")
+	path, _ := frecency.Path()
+	store, err := frecency.Load(path)
+	if err != nil || store.SessionScore("", "apx1", time.Now()) == 0 {
+		t.Errorf("Expected the attach to be recorded, got %+v (err %v)", store, err)
+	}
+}
+
+func TestTreeJumpRecordsVisit(t *testing.T) {
+	m, runner := newTestModel(t, tmux.Session{Name: "api", Windows: 1})
+	runner.Respond("list-windows", "0\teditor\t1\t1\tc5a1,80x24,0,0,1\n", nil)
+
+	model, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("t")})
+	m = update(t, model.(MainModel), cmd())
+	m = press(t, m, "j")
+	_, cmd = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if msgs := runCmd(cmd); len(msgs) != 1 || msgs[0] != tea.Quit() {
+		t.Errorf("Expected to quit once the visit is recorded, got %v", msgs)
+	}
+
+	path, _ := frecency.Path()
+	store, err := frecency.Load(path)
+	if err != nil || store.SessionScore("", "api", time.Now()) == 0 {
+		t.Errorf("Expected the jump to be recorded as a visit of api, got %+v (err %v)", store, err)
+	}
+}
+
+// Sessions are sorted within the group of their server, and sessions of
+// the same name on different servers have a history of their own.
+func TestSessionOrderByServer(t *testing.T) {
+	t.Setenv("TMUX", "")
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	servers := []*tmux.Client{tmux.NewServerClient("work", tmux.NewFakeRunner()), tmux.NewServerClient("home", tmux.NewFakeRunner())}
+	m := NewMainModel(config.DefaultConfig(), servers)
+	m.frecency.Visit("home", "b2", "", time.Now())
+	m.frecency.Visit("home", "api", "", time.Now())
+	m = update(t, m, tea.WindowSizeMsg{Width: 100, Height: 40})
+	m = update(t, m, sessionsLoadedMsg{sessions: []tmux.Session{
+		{Name: "a1", Server: "work"}, {Name: "a2", Server: "work"}, {Name: "api", Server: "work"},
+		{Name: "api", Server: "home"}, {Name: "b1", Server: "home"}, {Name: "b2", Server: "home"},
+	}})
+
+	var got []string
+	for _, item := range m.list.Items() {
+		session := item.(listItem).data.(tmux.Session)
+		got = append(got, session.Name+"@"+session.Server)
+	}
+	want := []string{"a1@work", "a2@work", "api@work", "api@home", "b2@home", "b1@home"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestCreateSessionRecordsVisit(t *testing.T) {
+	dir := t.TempDir()
+	m, _ := newTestModel(t)
+	m.frecency.Visit("", "", dir, time.Now())
+
+	m = press(t, m, "c", "enter")
+	m = update(t, m, reposLoadedMsg{{Name: "api", Path: "/src/api"}, {Name: "web", Path: dir}})
+	if repo, _ := m.highlightedRepo(); repo.Path != dir {
+		t.Fatalf("Expected the repository used before first, got %q", repo.Path)
+	}
+
+	m = press(t, m, "enter")
+	model, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = model.(MainModel)
+	path, _ := frecency.Path()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the history file to be written by a command, not by Update (err %v)", err)
+	}
+
+	msgs := runCmd(cmd)
+	store, err := frecency.Load(path)
+	if err != nil || store.Paths[dir].Rank != 1 || store.SessionScore("", "web", time.Now()) == 0 {
+		t.Errorf("Expected the new session to be recorded, got %+v (err %v)", store, err)
+	}
+	if len(msgs) != 1 || msgs[0] != tea.Quit() {
+		t.Errorf("Expected to quit once the visit is recorded, got %v", msgs)
+	}
+
+	// A history that can't be written is reported rather than ignored
+	state := filepath.Join(t.TempDir(), "state")
+	if err := os.WriteFile(state, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_STATE_HOME", state)
+	if msgs := runCmd(m.recordVisit("", "web", dir)); len(msgs) != 1 || !strings.HasPrefix(string(msgs[0].(errorMsg)), "Failed to record the visit") {
+		t.Errorf("Expected an error message, got %v", msgs)
+	}
+}
+
+// runCmd runs a command, and the commands it batches or sequences, and
+// returns the messages they produce.
+func runCmd(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	msg := cmd()
+	if msg == nil {
+		return nil
+	}
+	// tea.Sequence returns an unexported list of commands
+	if v := reflect.ValueOf(msg); v.Kind() == reflect.Slice && v.Type().Elem() == reflect.TypeOf(cmd) {
+		var msgs []tea.Msg
+		for i := range v.Len() {
+			msgs = append(msgs, runCmd(v.Index(i).Interface().(tea.Cmd))...)
+		}
+		return msgs
+	}
+	return []tea.Msg{msg}
+}
+
+func TestZoxideDirectories(t *testing.T) {
+	notes := filepath.Join(t.TempDir(), "my.notes")
+	if err := os.Mkdir(notes, 0755); err != nil {
+		t.Fatal(err)
+	}
+	m, runner := newTestModel(t)
+	m.frecency.Visit("", "", "/src/api", time.Now())
+
+	m = press(t, m, "c", "enter")
+	m = update(t, m, reposLoadedMsg{
+		{Name: "api", Path: "/src/api", Marker: git.GitMarker},
+		{Name: "my.notes", Path: notes, Marker: zoxide.Marker, Score: 20.5},
+		{Name: "web", Path: "/src/web", Marker: git.GitMarker, Score: 1},
+	})
+
+	var items []string
+	for _, item := range m.list.Items() {
+		items = append(items, item.(listItem).title+": "+item.(listItem).desc)
+	}
+	// The zoxide scores add to the muxyard ones
+	want := []string{
+		"my.notes (zoxide): " + notes + " • zoxide 20.5",
+		"api: /src/api • reading status…",
+		"web: /src/web • reading status… • zoxide 1.0",
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(items, "\n"))
+	}
+
+	m = press(t, m, "enter", "enter")
+	assertCommands(t, runner,
+		"new-session -d -s my_notes -P -F #{window_id} #{pane_id} -c "+notes+" -n main",
+		"attach-session -t my_notes",
+	)
+}
+
+func TestZoxideIndex(t *testing.T) {
+	root := t.TempDir()
+	api := filepath.Join(root, "api")
+	notes := filepath.Join(t.TempDir(), "notes")
+	for _, dir := range []string{filepath.Join(api, ".git"), notes} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A stand-in for zoxide, which is asked before its database is read
+	bin := t.TempDir()
+	script := "#!/bin/sh\necho '  20.5 " + notes + "'\necho '   3.0 " + api + "'\n"
+	if err := os.WriteFile(filepath.Join(bin, "zoxide"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	m, _ := newTestModel(t)
+	enabled := true
+	m.cfg.RepoDirectories = []config.RepoDirectory{{Path: root}}
+	m.cfg.Zoxide = config.ZoxideConfig{Enabled: &enabled}
+
+	want := []git.Repository{
+		{Name: "api", Path: api, Marker: git.GitMarker, Score: 3},
+		{Name: "notes", Path: notes, Marker: zoxide.Marker, Score: 20.5},
+	}
+	repos, ok := runCmd(m.scanRepositories())[0].(reposLoadedMsg)
+	if !ok || !reflect.DeepEqual([]git.Repository(repos), want) {
+		t.Errorf("Expected the zoxide directories in the scan, got %+v", repos)
+	}
+
+	// Only the scan is kept, and zoxide is merged into the cached list
+	path, err := git.IndexPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached, _ := git.LoadIndex(path, m.cfg.RepoDirectories, m.cfg.Markers())
+	if len(cached) != 1 || cached[0].Path != api || cached[0].Score != 0 {
+		t.Errorf("Expected only the scanned repository in the index, got %+v", cached)
+	}
+	m = press(t, m, "c", "enter")
+	if !reflect.DeepEqual(m.repos, want) {
+		t.Errorf("Expected the zoxide directories in the cached list, got %+v", m.repos)
+	}
+}
+
+func TestUnreachableServer(t *testing.T) {
+	t.Setenv("TMUX", "")
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	work := tmux.NewFakeRunner()
+	work.Respond("list-sessions", "api:1:0:1700000000:/src/api\n", nil)
+	scratch := tmux.NewFakeRunner()
+	scratch.Respond("list-sessions", "", errors.New("no server running"))
+	servers := []*tmux.Client{
+		tmux.NewServerClient("work", work),
+		tmux.NewServerClient("scratch", scratch),
+	}
+
+	m := NewMainModel(config.DefaultConfig(), servers)
+	m = update(t, m, tea.WindowSizeMsg{Width: 100, Height: 40})
+	m = update(t, m, loadSessions(servers)())
+
+	if len(m.sessions) != 1 || m.sessions[0].Name != "api" {
+		t.Errorf("Expected the work sessions to be listed, got %+v", m.sessions)
+	}
+	if view := m.View(); !strings.Contains(view, "Failed to load sessions from scratch: no server running") {
+		t.Errorf("Expected the unreachable server to be reported, got\n%s", view)
+	}
+
+	// The failure stays shown past key presses, until a load reaches it
+	m = press(t, m, "j")
+	if len(m.serverErrors) != 1 {
+		t.Error("Expected the server error to outlast a key press")
+	}
+	servers[1] = tmux.NewServerClient("scratch", tmux.NewFakeRunner())
+	m = update(t, m, loadSessions(servers)())
+	if len(m.serverErrors) != 0 {
+		t.Errorf("Expected the server error to clear, got %q", m.serverErrors)
+	}
+}
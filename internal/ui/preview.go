@@ -0,0 +1,136 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"muxyard/internal/tmux"
+)
+
+const (
+	previewInterval = 2 * time.Second
+	// Below this terminal width the preview would squeeze the session list
+	// too much, so it is hidden.
+	previewMinWidth = 100
+)
+
+type previewLoadedMsg struct {
+	session tmux.Session
+	content string
+	windows []tmux.Window
+	err     error
+}
+
+type previewTickMsg struct{}
+
+func previewTick() tea.Cmd {
+	return tea.Tick(previewInterval, func(time.Time) tea.Msg {
+		return previewTickMsg{}
+	})
+}
+
+func loadPreview(client *tmux.Client, session tmux.Session) tea.Cmd {
+	return func() tea.Msg {
+		windows, err := client.ListWindows(session.Name)
+		if err != nil {
+			return previewLoadedMsg{session: session, err: err}
+		}
+		content, err := client.CapturePane(session.Name)
+		return previewLoadedMsg{session: session, content: content, windows: windows, err: err}
+	}
+}
+
+// selectedSessionForPreview returns the session under the cursor when the
+// preview is visible.
+func (m MainModel) selectedSessionForPreview() (tmux.Session, bool) {
+	if !m.previewVisible() || m.state != sessionListView {
+		return tmux.Session{}, false
+	}
+	idx := m.list.Index()
+	if idx < 0 || idx >= len(m.filteredSessions) {
+		return tmux.Session{}, false
+	}
+	return m.filteredSessions[idx], true
+}
+
+// refreshPreview loads the preview for the selected session, if any.
+func (m MainModel) refreshPreview() tea.Cmd {
+	session, ok := m.selectedSessionForPreview()
+	if !ok {
+		return nil
+	}
+	return loadPreview(m.client(session), session)
+}
+
+func (m MainModel) previewVisible() bool {
+	return m.showPreview && m.width >= previewMinWidth
+}
+
+func (m MainModel) previewWidth() int {
+	return m.width / 2
+}
+
+func (m MainModel) renderPreview(height int) string {
+	style := m.styles.Border.Padding(0, 1)
+	width := m.previewWidth() - style.GetHorizontalFrameSize()
+	height -= style.GetVerticalFrameSize()
+	if width <= 0 || height <= 0 {
+		return ""
+	}
+	// lipgloss widths include padding but not borders
+	style = style.Width(width + style.GetHorizontalPadding()).Height(height)
+
+	session, ok := m.selectedSessionForPreview()
+	if !ok || !sameSession(session, m.previewSession) {
+		return style.Render(m.styles.Dimmed.Render("No preview"))
+	}
+
+	var lines []string
+	if m.previewError != "" {
+		lines = append(lines, m.styles.Error.Render(m.previewError))
+	}
+
+	for _, window := range m.previewWindows {
+		line := fmt.Sprintf("%d: %s (%d panes)", window.Index, window.Name, window.Panes)
+		if window.Active {
+			lines = append(lines, m.styles.Selected.Render(line+" *"))
+		} else {
+			lines = append(lines, m.styles.Dimmed.Render(line))
+		}
+	}
+	if len(lines) > 0 {
+		lines = append(lines, m.styles.Dimmed.Render(strings.Repeat("─", width)))
+	}
+
+	// Show the bottom of the pane, which is where prompts and the most
+	// recent output live.
+	content := strings.Split(strings.TrimRight(m.previewContent, "\n "), "\n")
+	if room := height - len(lines); room < len(content) {
+		content = content[max(0, len(content)-room):]
+	}
+	for _, line := range content {
+		lines = append(lines, ansi.Truncate(line, width, "")+"\x1b[0m")
+	}
+
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+
+	return style.Render(strings.Join(lines, "\n"))
+}
+
+// withPreview places the preview panel to the right of the session list.
+func (m MainModel) withPreview(listView string) string {
+	if !m.previewVisible() {
+		return listView
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, listView, m.renderPreview(lipgloss.Height(listView)))
+}
+
+func sameSession(a, b tmux.Session) bool {
+	return a.Name == b.Name && a.Server == b.Server
+}
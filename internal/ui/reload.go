@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"muxyard/internal/config"
+)
+
+const configInterval = time.Second
+
+// configCheckedMsg carries a reloaded config, or neither a config nor an
+// error when the config files haven't changed.
+type configCheckedMsg struct {
+	cfg *config.Config
+	err error
+}
+
+// watchConfig checks the config files for changes once, reloading them if
+// they changed.
+func watchConfig(watcher *config.Watcher) tea.Cmd {
+	return tea.Tick(configInterval, func(time.Time) tea.Msg {
+		if !watcher.Changed() {
+			return configCheckedMsg{}
+		}
+		cfg, err := config.Reload()
+		return configCheckedMsg{cfg: cfg, err: err}
+	})
+}
+
+// applyConfig switches to a reloaded config. A config with problems is not
+// applied; the first problem is shown instead and the previous config stays.
+func (m MainModel) applyConfig(msg configCheckedMsg) MainModel {
+	switch {
+	case msg.err != nil:
+		m.error = fmt.Sprintf("Config not reloaded: %v", msg.err)
+		return m
+	case msg.cfg == nil:
+		return m
+	case len(msg.cfg.Issues) > 0:
+		m.error = fmt.Sprintf("Config not reloaded: %s", msg.cfg.Issues[0])
+		if more := len(msg.cfg.Issues) - 1; more > 0 {
+			m.error += fmt.Sprintf(" (and %d more)", more)
+		}
+		return m
+	}
+
+	cfg := msg.cfg
+	// The servers were chosen at startup, possibly by command line flags
+	cfg.Tmux = m.cfg.Tmux
+	m.cfg = cfg
+	m.templates = cfg.Templates
+	m.styles = NewStyles(cfg.Theme, cfg.Colors)
+	m.keys = NewKeyMap(cfg.Keys)
+	m.list.KeyMap = listKeyMap(m.list.KeyMap, m.keys)
+	m.spinner.Style = m.styles.Spinner
+	m.configIssues = nil
+	m = m.resize()
+
+	if m.state == templateSelectView {
+		m = m.updateTemplateList()
+	}
+	m.success = "Config reloaded"
+	return m
+}
@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"muxyard/internal/snapshot"
+	"muxyard/internal/tmux"
+)
+
+// enterRestore lists the saved sessions of the first server that aren't
+// running anymore.
+func (m MainModel) enterRestore() (tea.Model, tea.Cmd) {
+	path, err := snapshot.Path(m.servers[0].Server)
+	if err != nil {
+		m.error = fmt.Sprintf("Failed to locate saved sessions: %v", err)
+		return m, nil
+	}
+
+	snap, err := snapshot.Load(path)
+	if err != nil {
+		m.error = fmt.Sprintf("Failed to load saved sessions: %v", err)
+		return m, nil
+	}
+
+	var running []tmux.Session
+	for _, session := range m.sessions {
+		if session.Server == m.servers[0].Server {
+			running = append(running, session)
+		}
+	}
+
+	m.savedSessions = snap.Missing(running)
+	m.state = restoreListView
+	return m.updateRestoreList(), nil
+}
+
+func (m MainModel) handleRestoreKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.MenuBack):
+		m.state = createModeView
+		return m.updateCreateModeList(), nil
+
+	case key.Matches(msg, m.keys.Select):
+		selectedIdx := m.list.Index()
+		if selectedIdx < 0 || selectedIdx >= len(m.savedSessions) {
+			return m, nil
+		}
+
+		session := m.savedSessions[selectedIdx]
+		if err := snapshot.Restore(m.servers[0], session); err != nil {
+			m.error = fmt.Sprintf("Failed to restore session: %v", err)
+			return m, nil
+		}
+
+		visit := m.recordVisit(m.servers[0].Server, session.Name, session.Path())
+		if err := m.servers[0].AttachToSession(session.Name); err != nil {
+			m.error = fmt.Sprintf("Failed to attach to session: %v", err)
+			return m, visit
+		}
+
+		m.quitting = true
+		return m, tea.Sequence(visit, tea.Quit)
+
+	case key.Matches(msg, m.keys.Down):
+		m.list.CursorDown()
+
+	case key.Matches(msg, m.keys.Up):
+		m.list.CursorUp()
+	}
+
+	return m, nil
+}
+
+func (m MainModel) updateRestoreList() MainModel {
+	items := make([]list.Item, len(m.savedSessions))
+	for i, session := range m.savedSessions {
+		items[i] = listItem{
+			title: session.Name,
+			desc:  fmt.Sprintf("%d windows in %s", len(session.Windows), session.Path()),
+			data:  session,
+		}
+	}
+	m.list.SetItems(items)
+	m.list.Title = "Restore Saved Session"
+	return m
+}
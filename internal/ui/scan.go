@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"muxyard/internal/config"
+	"muxyard/internal/git"
+	"muxyard/internal/zoxide"
+)
+
+// loadRepositories scans the directories for projects, keeps the result as
+// the index for the next visit to the repo list, and adds the ones zoxide
+// knows if asked to. When zoxide can't be read, the scanned projects are
+// still listed.
+func loadRepositories(directories []config.RepoDirectory, markers []string, zoxideConfig config.ZoxideConfig) tea.Cmd {
+	return func() tea.Msg {
+		repos, err := git.FindRepositories(directories, markers)
+		if err != nil {
+			return errorMsg(fmt.Sprintf("Failed to find repositories: %v", err))
+		}
+
+		if path, err := git.IndexPath(); err == nil {
+			// Without an index the next visit only waits for the scan
+			_ = git.SaveIndex(path, directories, markers, repos)
+		}
+
+		repos, err = withZoxide(repos, zoxideConfig)
+		if err != nil {
+			return tea.BatchMsg{
+				func() tea.Msg { return reposLoadedMsg(repos) },
+				func() tea.Msg { return errorMsg(fmt.Sprintf("Failed to read zoxide directories: %v", err)) },
+			}
+		}
+		return reposLoadedMsg(repos)
+	}
+}
+
+// withZoxide adds the directories zoxide knows to repos, if asked to. The
+// index only holds what the scan found, as the scores change between
+// visits and zoxide may have been turned off since.
+func withZoxide(repos []git.Repository, zoxideConfig config.ZoxideConfig) ([]git.Repository, error) {
+	if !zoxideConfig.On() {
+		return repos, nil
+	}
+	entries, err := zoxide.Query()
+	if err != nil {
+		return repos, err
+	}
+	return zoxide.Merge(repos, entries, zoxideConfig.MinScore), nil
+}
+
+// scanRepositories is loadRepositories for the current config.
+func (m MainModel) scanRepositories() tea.Cmd {
+	return loadRepositories(m.cfg.RepoDirectories, m.cfg.Markers(), m.cfg.Zoxide)
+}
+
+// enterRepoList shows the repositories found by the last scan right away,
+// while a new scan runs in the background. Without an index of the last
+// scan, the list waits for the new one.
+func (m MainModel) enterRepoList() (tea.Model, tea.Cmd) {
+	var repos []git.Repository
+	if path, err := git.IndexPath(); err == nil {
+		repos, _ = git.LoadIndex(path, m.cfg.RepoDirectories, m.cfg.Markers())
+	}
+	if len(repos) == 0 {
+		m.state = loadingView
+		return m, m.scanRepositories()
+	}
+	// A failure shows when the scan reads zoxide again
+	repos, _ = withZoxide(repos, m.cfg.Zoxide)
+
+	m.repos = repos
+	m.scanning = true
+	m = m.filterRepos()
+	return m.updateRepoList(), tea.Batch(loadRepoStatuses(repos), m.scanRepositories())
+}
+
+// applyRepos takes the result of a scan. Statuses are read for the
+// repositories that are new to the list, or for all of them after a rescan
+// asked for by the user.
+func (m MainModel) applyRepos(repos []git.Repository) (tea.Model, tea.Cmd) {
+	gone := make(map[string]bool, len(m.repos))
+	for _, repo := range m.repos {
+		gone[repo.Path] = true
+	}
+	var added []git.Repository
+	for _, repo := range repos {
+		if gone[repo.Path] {
+			delete(gone, repo.Path)
+		} else {
+			added = append(added, repo)
+		}
+	}
+
+	load := added
+	if m.rescanning {
+		load = repos
+	}
+	m.repos = repos
+
+	if m.state == repoListView {
+		switch {
+		case len(added) > 0 || len(gone) > 0:
+			m.success = fmt.Sprintf("Repositories updated: %d new, %d gone", len(added), len(gone))
+		case m.rescanning:
+			m.success = "Repositories up to date"
+		}
+	}
+	m.scanning, m.rescanning = false, false
+
+	switch m.state {
+	case loadingView:
+		m = m.filterRepos().updateRepoList()
+	case repoListView:
+		m = m.refreshRepoList()
+	default:
+		// The list is redrawn when going back to it
+		m = m.filterRepos()
+	}
+	return m, loadRepoStatuses(load)
+}
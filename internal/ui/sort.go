@@ -0,0 +1,143 @@
+package ui
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"muxyard/internal/frecency"
+	"muxyard/internal/git"
+	"muxyard/internal/tmux"
+)
+
+// sortMode is the order of the session or repository list.
+type sortMode int
+
+const (
+	sortByFrecency sortMode = iota
+	sortByName
+	// sortByActivity puts the sessions used last first, and the
+	// repositories committed to last.
+	sortByActivity
+	sortByWindows
+)
+
+// sessionSorts and repoSorts are the orders the sort key cycles through.
+var (
+	sessionSorts = []sortMode{sortByFrecency, sortByName, sortByActivity, sortByWindows}
+	repoSorts    = []sortMode{sortByFrecency, sortByName, sortByActivity}
+)
+
+func nextSort(modes []sortMode, current sortMode) sortMode {
+	return modes[(slices.Index(modes, current)+1)%len(modes)]
+}
+
+// frecencyWeight is how many points of fuzzy match score doubling the
+// frecency of an entry is worth when filtering.
+const frecencyWeight = 10
+
+// blend ranks a fuzzy match by its score and the frecency of its entry, so
+// that the places used most win between similar matches.
+func blend(score int, frecency float64) float64 {
+	return float64(score) + frecencyWeight*math.Log2(1+frecency)
+}
+
+// loadFrecency reads the usage history. Without one the lists are in name
+// order, as if nothing was used yet.
+func loadFrecency() (*frecency.Store, string) {
+	empty := &frecency.Store{Version: frecency.Version}
+	path, err := frecency.Path()
+	if err != nil {
+		return empty, ""
+	}
+	store, err := frecency.Load(path)
+	if err != nil {
+		return empty, fmt.Sprintf("Failed to load usage history: %v", err)
+	}
+	return store, ""
+}
+
+// repoFrecency is how much a repository was used: through muxyard, and as
+// far as zoxide tells, from the shell. Both weigh visits alike.
+func (m MainModel) repoFrecency(repo git.Repository, now time.Time) float64 {
+	return m.frecency.PathScore(repo.Path, now) + repo.Score
+}
+
+// recordVisit adds a use of a session on a server, and of the directory it
+// was started in if known, to the usage history. The history in memory is updated right
+// away; the returned command records the visit in the history file, which
+// it reloads first since other muxyard processes update it too.
+func (m MainModel) recordVisit(server, session, path string) tea.Cmd {
+	m.frecency.Visit(server, session, path, time.Now())
+	return func() tea.Msg {
+		if err := frecency.Record(server, session, path); err != nil {
+			return errorMsg(fmt.Sprintf("Failed to record the visit: %v", err))
+		}
+		return nil
+	}
+}
+
+// filterSessions lists the sessions matching the filter query in the chosen
+// order, within the group of each server in the configured order. While
+// filtering, the frecency order is that of the blended match scores.
+func (m MainModel) filterSessions() MainModel {
+	sessions := slices.Clone(m.fuzzyFilterSessions(m.filterQuery))
+	now := time.Now()
+	switch m.sessionSort {
+	case sortByFrecency:
+		if m.filterQuery == "" {
+			slices.SortStableFunc(sessions, func(a, b tmux.Session) int {
+				return cmp.Compare(m.frecency.SessionScore(b.Server, b.Name, now), m.frecency.SessionScore(a.Server, a.Name, now))
+			})
+		}
+	case sortByName:
+		if m.filterQuery == "" {
+			slices.SortStableFunc(sessions, func(a, b tmux.Session) int { return strings.Compare(a.Name, b.Name) })
+		}
+	case sortByActivity:
+		slices.SortStableFunc(sessions, func(a, b tmux.Session) int { return b.Activity.Compare(a.Activity) })
+	case sortByWindows:
+		slices.SortStableFunc(sessions, func(a, b tmux.Session) int { return cmp.Compare(b.Windows, a.Windows) })
+	}
+	slices.SortStableFunc(sessions, func(a, b tmux.Session) int {
+		return cmp.Compare(m.serverIndex(a.Server), m.serverIndex(b.Server))
+	})
+	m.filteredSessions = sessions
+	return m
+}
+
+// serverIndex returns the position of a server among the configured ones.
+func (m MainModel) serverIndex(server string) int {
+	return slices.IndexFunc(m.servers, func(c *tmux.Client) bool { return c.Server == server })
+}
+
+// filterRepos lists the repositories matching the filter query, only the
+// dirty ones if asked, in the chosen order. While filtering, the frecency
+// order is that of the blended match scores.
+func (m MainModel) filterRepos() MainModel {
+	repos := slices.Clone(m.fuzzyFilterRepos(m.repoFilterQuery))
+	if m.dirtyOnly {
+		repos = slices.DeleteFunc(repos, func(r git.Repository) bool {
+			return !m.repoStatus[r.Path].status.Dirty
+		})
+	}
+	now := time.Now()
+	switch m.repoSort {
+	case sortByFrecency:
+		if m.repoFilterQuery == "" {
+			slices.SortStableFunc(repos, func(a, b git.Repository) int {
+				return cmp.Compare(m.repoFrecency(b, now), m.repoFrecency(a, now))
+			})
+		}
+	case sortByActivity:
+		slices.SortStableFunc(repos, func(a, b git.Repository) int {
+			return m.repoStatus[b.Path].status.LastCommit.Compare(m.repoStatus[a.Path].status.LastCommit)
+		})
+	}
+	m.filteredRepos = repos
+	return m
+}
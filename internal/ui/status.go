@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"muxyard/internal/config"
+	"muxyard/internal/git"
+)
+
+// repoStatus is the status of one repository, once read.
+type repoStatus struct {
+	status git.Status
+	err    error
+}
+
+type repoStatusMsg struct {
+	path string
+	repoStatus
+}
+
+// statusSlots bounds the git processes reading statuses at once, so that a
+// large list doesn't start hundreds of them.
+var statusSlots = make(chan struct{}, max(runtime.NumCPU(), 4))
+
+// loadRepoStatuses reads the status of every git repository in the
+// background. Each status arrives in a message of its own, so the list fills
+// in as they come.
+func loadRepoStatuses(repos []git.Repository) tea.Cmd {
+	var cmds []tea.Cmd
+	for _, repo := range repos {
+		if !repo.IsGit() {
+			continue
+		}
+		cmds = append(cmds, func() tea.Msg {
+			statusSlots <- struct{}{}
+			defer func() { <-statusSlots }()
+			status, err := git.RepoStatus(repo)
+			return repoStatusMsg{path: repo.Path, repoStatus: repoStatus{status: status, err: err}}
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+// applyRepoStatus records a status and redraws the repository list.
+func (m MainModel) applyRepoStatus(msg repoStatusMsg) MainModel {
+	m.repoStatus[msg.path] = msg.repoStatus
+	if m.state != repoListView {
+		return m
+	}
+	return m.refreshRepoList()
+}
+
+// refreshRepoList redraws the repository list, keeping the cursor on the
+// same repository.
+func (m MainModel) refreshRepoList() MainModel {
+	highlighted, ok := m.highlightedRepo()
+	m = m.filterRepos().updateRepoList()
+	if ok {
+		if i := slices.IndexFunc(m.filteredRepos, func(r git.Repository) bool { return r.Path == highlighted.Path }); i >= 0 {
+			m.list.Select(i)
+		}
+	}
+	return m
+}
+
+// describeStatus summarizes a repository's status for the list, e.g.
+// "main • dirty • ↑1 ↓2 • committed 3h ago". Projects other than git
+// repositories have no status.
+func (m MainModel) describeStatus(repo git.Repository, now time.Time) string {
+	rs, ok := m.repoStatus[repo.Path]
+	switch {
+	case !repo.IsGit():
+		return ""
+	case !ok:
+		return "reading status…"
+	case rs.err != nil:
+		return "status unavailable"
+	}
+
+	status := rs.status
+	parts := []string{status.Branch}
+	if repo.Kind != git.Bare {
+		if status.Dirty {
+			parts = append(parts, "dirty")
+		} else {
+			parts = append(parts, "clean")
+		}
+	}
+	if status.Ahead > 0 || status.Behind > 0 {
+		parts = append(parts, fmt.Sprintf("↑%d ↓%d", status.Ahead, status.Behind))
+	}
+	if !status.LastCommit.IsZero() {
+		parts = append(parts, "committed "+ago(status.LastCommit, now))
+	}
+	return strings.Join(parts, " • ")
+}
+
+// ago describes how long ago t was, coarsely.
+func ago(t, now time.Time) string {
+	switch d := now.Sub(t); {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	default:
+		return "on " + t.Format("2006-01-02")
+	}
+}
+
+// badges name the kinds of projects found by markers other than .git.
+var badges = map[string]string{
+	".hg":              "hg",
+	".jj":              "jj",
+	".svn":             "svn",
+	"go.mod":           "go",
+	"package.json":     "node",
+	"Cargo.toml":       "rust",
+	"pyproject.toml":   "python",
+	config.ProjectFile: "muxyard",
+}
+
+// badge names the kind of a project by its marker, or by the marker itself
+// when it is none of the usual ones.
+func badge(repo git.Repository) string {
+	if b, ok := badges[repo.Marker]; ok {
+		return b
+	}
+	return repo.Marker
+}
@@ -1,7 +1,10 @@
 package ui
 
 import (
+	"os"
+
 	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 	"muxyard/internal/config"
 )
 
@@ -20,58 +23,88 @@ type Styles struct {
 	FilterBorder lipgloss.Style
 }
 
-func NewStyles(colors config.ColorConfig) Styles {
+// NewStyles builds the styles of a theme, with colors overriding single
+// colors of it. An unknown theme falls back to the default one. Colors are
+// reduced to what the terminal supports, and with NO_COLOR set only bold and
+// reverse video are used.
+func NewStyles(theme string, colors config.ColorConfig) Styles {
+	p, ok := themes[theme]
+	if !ok {
+		p = themes["default"]
+	}
+
+	renderer := lipgloss.DefaultRenderer()
+	if os.Getenv("NO_COLOR") != "" {
+		// The default renderer honors NO_COLOR by dropping all styling,
+		// which would leave the title and selection indistinguishable
+		p, colors = themes["monochrome"], config.ColorConfig{}
+		renderer = lipgloss.NewRenderer(os.Stdout)
+		renderer.SetColorProfile(termenv.ANSI)
+	}
+
+	color := func(themed lipgloss.TerminalColor, configured string) lipgloss.TerminalColor {
+		if configured == "" {
+			return themed
+		}
+		return lipgloss.Color(configured)
+	}
+
+	title := renderer.NewStyle().
+		Bold(true).
+		Foreground(color(p.titleForeground, colors.Title.Foreground)).
+		Background(color(p.titleBackground, colors.Title.Background)).
+		Padding(0, 1)
+	if _, ok := title.GetBackground().(lipgloss.NoColor); ok {
+		title = title.Reverse(true)
+	}
+
 	return Styles{
-		Title: lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color(colors.Title.Foreground)).
-			Background(lipgloss.Color(colors.Title.Background)).
-			Padding(0, 1),
+		Title: title,
 
-		Selected: lipgloss.NewStyle().
+		Selected: renderer.NewStyle().
 			Bold(true).
-			Foreground(lipgloss.Color(colors.Selected)),
+			Foreground(color(p.selected, colors.Selected)),
 
-		Dimmed: lipgloss.NewStyle().
-			Foreground(lipgloss.Color(colors.Dimmed)),
+		Dimmed: renderer.NewStyle().
+			Foreground(color(p.muted, colors.Dimmed)),
 
-		Help: lipgloss.NewStyle().
-			Foreground(lipgloss.Color(colors.Help)).
+		Help: renderer.NewStyle().
+			Foreground(color(p.muted, colors.Help)).
 			Margin(1, 0),
 
-		Error: lipgloss.NewStyle().
-			Foreground(lipgloss.Color(colors.Error)).
+		Error: renderer.NewStyle().
+			Foreground(color(p.err, colors.Error)).
 			Bold(true),
 
-		Success: lipgloss.NewStyle().
-			Foreground(lipgloss.Color(colors.Success)).
+		Success: renderer.NewStyle().
+			Foreground(color(p.success, colors.Success)).
 			Bold(true),
 
-		Border: lipgloss.NewStyle().
+		Border: renderer.NewStyle().
 			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color(colors.Border)).
+			BorderForeground(color(p.border, colors.Border)).
 			Padding(1, 2),
 
-		Input: lipgloss.NewStyle().
+		Input: renderer.NewStyle().
 			Border(lipgloss.NormalBorder()).
-			BorderForeground(lipgloss.Color(colors.Input)).
+			BorderForeground(color(p.border, colors.Input)).
 			Padding(0, 1),
 
-		FocusedInput: lipgloss.NewStyle().
+		FocusedInput: renderer.NewStyle().
 			Border(lipgloss.NormalBorder()).
-			BorderForeground(lipgloss.Color(colors.FocusedInput)).
+			BorderForeground(color(p.accent, colors.FocusedInput)).
 			Padding(0, 1),
 
-		Spinner: lipgloss.NewStyle().
-			Foreground(lipgloss.Color(colors.Spinner)),
+		Spinner: renderer.NewStyle().
+			Foreground(color(p.accent, colors.Spinner)),
 
-		Highlight: lipgloss.NewStyle().
-			Foreground(lipgloss.Color(colors.Highlight)).
+		Highlight: renderer.NewStyle().
+			Foreground(color(p.accent, colors.Highlight)).
 			Bold(true),
 
-		FilterBorder: lipgloss.NewStyle().
+		FilterBorder: renderer.NewStyle().
 			Border(lipgloss.NormalBorder()).
-			BorderForeground(lipgloss.Color(colors.FilterBorder)).
+			BorderForeground(color(p.accent, colors.FilterBorder)).
 			Padding(0, 1),
 	}
 }
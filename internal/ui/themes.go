@@ -0,0 +1,100 @@
+package ui
+
+import "github.com/charmbracelet/lipgloss"
+
+// palette is the handful of colors a theme is made of; NewStyles assigns
+// them to the parts of the UI.
+type palette struct {
+	titleForeground lipgloss.TerminalColor
+	titleBackground lipgloss.TerminalColor
+	selected        lipgloss.TerminalColor
+	muted           lipgloss.TerminalColor
+	err             lipgloss.TerminalColor
+	success         lipgloss.TerminalColor
+	border          lipgloss.TerminalColor
+	accent          lipgloss.TerminalColor
+}
+
+// shade is a color with hand-picked stand-ins for 256 and 16 color
+// terminals, where the nearest match lipgloss would compute is often a
+// poor one.
+func shade(trueColor, ansi256, ansi string) lipgloss.CompleteColor {
+	return lipgloss.CompleteColor{TrueColor: trueColor, ANSI256: ansi256, ANSI: ansi}
+}
+
+// adaptive picks between two shades by the terminal's background.
+func adaptive(light, dark lipgloss.CompleteColor) lipgloss.CompleteAdaptiveColor {
+	return lipgloss.CompleteAdaptiveColor{Light: light, Dark: dark}
+}
+
+// themes holds the built-in themes named by config.Themes.
+var themes = map[string]palette{
+	"default": {
+		titleForeground: shade("#FAFAFA", "231", "15"),
+		titleBackground: shade("#7D56F4", "99", "5"),
+		selected:        adaptive(shade("#B93FC4", "170", "5"), shade("#EE6FF8", "213", "13")),
+		muted:           adaptive(shade("#8A8A8A", "245", "8"), shade("#626262", "241", "8")),
+		err:             adaptive(shade("#D70000", "160", "1"), shade("#FF0000", "196", "9")),
+		success:         adaptive(shade("#008700", "28", "2"), shade("#00FF00", "46", "10")),
+		border:          shade("#874BFD", "99", "5"),
+		accent:          adaptive(shade("#D7478F", "168", "5"), shade("#FF75B7", "211", "13")),
+	},
+
+	"dark": {
+		titleForeground: shade("#FFFFFF", "231", "15"),
+		titleBackground: shade("#5F5FD7", "62", "4"),
+		selected:        shade("#87D7FF", "117", "14"),
+		muted:           shade("#6C6C6C", "242", "8"),
+		err:             shade("#FF5F5F", "203", "9"),
+		success:         shade("#87D787", "114", "10"),
+		border:          shade("#5F5FD7", "62", "4"),
+		accent:          shade("#FFD75F", "221", "11"),
+	},
+
+	"light": {
+		titleForeground: shade("#FFFFFF", "231", "15"),
+		titleBackground: shade("#005FAF", "25", "4"),
+		selected:        shade("#005FAF", "25", "4"),
+		muted:           shade("#808080", "244", "8"),
+		err:             shade("#D70000", "160", "1"),
+		success:         shade("#008700", "28", "2"),
+		border:          shade("#0087AF", "31", "6"),
+		accent:          shade("#AF5F00", "130", "3"),
+	},
+
+	// Monochrome relies on bold and reverse video alone
+	"monochrome": {
+		titleForeground: lipgloss.NoColor{},
+		titleBackground: lipgloss.NoColor{},
+		selected:        lipgloss.NoColor{},
+		muted:           lipgloss.NoColor{},
+		err:             lipgloss.NoColor{},
+		success:         lipgloss.NoColor{},
+		border:          lipgloss.NoColor{},
+		accent:          lipgloss.NoColor{},
+	},
+
+	// Solarized uses the same accents on both backgrounds
+	"solarized": {
+		titleForeground: shade("#FDF6E3", "230", "15"),
+		titleBackground: shade("#268BD2", "33", "4"),
+		selected:        shade("#B58900", "136", "3"),
+		muted:           adaptive(shade("#93A1A1", "245", "8"), shade("#586E75", "240", "8")),
+		err:             shade("#DC322F", "160", "1"),
+		success:         shade("#859900", "64", "2"),
+		border:          shade("#6C71C4", "61", "5"),
+		accent:          shade("#D33682", "125", "5"),
+	},
+
+	// Catppuccin Latte on light backgrounds, Mocha on dark ones
+	"catppuccin": {
+		titleForeground: adaptive(shade("#EFF1F5", "255", "15"), shade("#1E1E2E", "234", "0")),
+		titleBackground: adaptive(shade("#8839EF", "93", "5"), shade("#CBA6F7", "183", "13")),
+		selected:        adaptive(shade("#EA76CB", "170", "5"), shade("#F5C2E7", "218", "13")),
+		muted:           adaptive(shade("#9CA0B0", "247", "8"), shade("#6C7086", "60", "8")),
+		err:             adaptive(shade("#D20F39", "161", "1"), shade("#F38BA8", "211", "9")),
+		success:         adaptive(shade("#40A02B", "70", "2"), shade("#A6E3A1", "151", "10")),
+		border:          adaptive(shade("#7287FD", "69", "4"), shade("#B4BEFE", "147", "12")),
+		accent:          adaptive(shade("#FE640B", "202", "3"), shade("#FAB387", "216", "11")),
+	},
+}
@@ -0,0 +1,225 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"muxyard/internal/tmux"
+)
+
+// treeNode is one row of the session tree: a session, one of its windows,
+// or one of a window's panes.
+type treeNode struct {
+	session tmux.Session
+	window  *tmux.Window
+	pane    *tmux.Pane
+}
+
+// target is the tmux target that switch-client jumps to for the node.
+func (n treeNode) target() string {
+	switch {
+	case n.pane != nil:
+		return fmt.Sprintf("%s:%d.%d", n.session.Name, n.pane.WindowIndex, n.pane.Index)
+	case n.window != nil:
+		return fmt.Sprintf("%s:%d", n.session.Name, n.window.Index)
+	}
+	return n.session.Name
+}
+
+// key identifies a node for tracking which nodes are expanded.
+func (n treeNode) key() string {
+	key := n.session.Server + "/" + n.session.Name
+	if n.window != nil {
+		key += fmt.Sprintf(":%d", n.window.Index)
+	}
+	return key
+}
+
+type sessionTree struct {
+	windows []tmux.Window
+	panes   []tmux.Pane
+}
+
+type treeLoadedMsg struct {
+	session tmux.Session
+	tree    sessionTree
+	err     error
+}
+
+func loadTree(client *tmux.Client, session tmux.Session) tea.Cmd {
+	return func() tea.Msg {
+		windows, err := client.ListWindows(session.Name)
+		if err != nil {
+			return treeLoadedMsg{session: session, err: err}
+		}
+		panes, err := client.ListPanes(session.Name)
+		return treeLoadedMsg{session: session, tree: sessionTree{windows: windows, panes: panes}, err: err}
+	}
+}
+
+// enterTree opens the tree view with the session under the cursor expanded.
+func (m MainModel) enterTree() (tea.Model, tea.Cmd) {
+	m.state = treeView
+	m.treeExpanded = make(map[string]bool)
+	m.trees = make(map[string]sessionTree)
+
+	var cmds []tea.Cmd
+	idx := m.list.Index()
+	if idx >= 0 && idx < len(m.filteredSessions) {
+		session := m.filteredSessions[idx]
+		m.treeExpanded[treeNode{session: session}.key()] = true
+		cmds = append(cmds, loadTree(m.client(session), session))
+	}
+
+	m = m.updateTreeList()
+	m.list.Select(max(0, idx))
+	return m, tea.Batch(cmds...)
+}
+
+func (m MainModel) treeNodes() []treeNode {
+	var nodes []treeNode
+	for _, session := range m.filteredSessions {
+		node := treeNode{session: session}
+		nodes = append(nodes, node)
+		if !m.treeExpanded[node.key()] {
+			continue
+		}
+
+		tree := m.trees[node.key()]
+		for i := range tree.windows {
+			windowNode := treeNode{session: session, window: &tree.windows[i]}
+			nodes = append(nodes, windowNode)
+			if !m.treeExpanded[windowNode.key()] {
+				continue
+			}
+
+			for j := range tree.panes {
+				if tree.panes[j].WindowIndex == tree.windows[i].Index {
+					nodes = append(nodes, treeNode{session: session, window: &tree.windows[i], pane: &tree.panes[j]})
+				}
+			}
+		}
+	}
+	return nodes
+}
+
+func (m MainModel) selectedTreeNode() (treeNode, bool) {
+	nodes := m.treeNodes()
+	idx := m.list.Index()
+	if idx < 0 || idx >= len(nodes) {
+		return treeNode{}, false
+	}
+	return nodes[idx], true
+}
+
+// handleTreeKeys checks expand and collapse before attach and back, which
+// share l and h by default.
+func (m MainModel) handleTreeKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Down):
+		m.list.CursorDown()
+
+	case key.Matches(msg, m.keys.Up):
+		m.list.CursorUp()
+
+	case key.Matches(msg, m.keys.Expand):
+		node, ok := m.selectedTreeNode()
+		if !ok || node.pane != nil || m.treeExpanded[node.key()] {
+			return m, nil
+		}
+		m.treeExpanded[node.key()] = true
+		if node.window == nil {
+			return m.updateTreeList(), loadTree(m.client(node.session), node.session)
+		}
+		return m.updateTreeList(), nil
+
+	case key.Matches(msg, m.keys.Collapse):
+		node, ok := m.selectedTreeNode()
+		if !ok {
+			return m, nil
+		}
+		if node.pane == nil && m.treeExpanded[node.key()] {
+			delete(m.treeExpanded, node.key())
+			return m.updateTreeList(), nil
+		}
+		// Collapse the parent and move the cursor onto it
+		parent := treeNode{session: node.session}
+		if node.pane != nil {
+			parent.window = node.window
+		}
+		delete(m.treeExpanded, parent.key())
+		m = m.updateTreeList()
+		for i, n := range m.treeNodes() {
+			if n.key() == parent.key() && n.pane == nil {
+				m.list.Select(i)
+				break
+			}
+		}
+		return m, nil
+
+	case key.Matches(msg, m.keys.Attach):
+		node, ok := m.selectedTreeNode()
+		if !ok {
+			return m, nil
+		}
+		visit := m.recordVisit(node.session.Server, node.session.Name, "")
+		if err := m.client(node.session).AttachToSession(node.target()); err != nil {
+			m.error = fmt.Sprintf("Failed to attach: %v", err)
+			return m, visit
+		}
+		m.quitting = true
+		return m, tea.Sequence(visit, tea.Quit)
+
+	case key.Matches(msg, m.keys.Back):
+		m.state = sessionListView
+		return m.updateSessionList(), m.refreshPreview()
+	}
+
+	return m, nil
+}
+
+func (m MainModel) updateTreeList() MainModel {
+	nodes := m.treeNodes()
+	items := make([]list.Item, len(nodes))
+	for i, node := range nodes {
+		items[i] = m.treeItem(node)
+	}
+	m.list.SetItems(items)
+	m.list.Title = "Session Tree"
+	return m
+}
+
+func (m MainModel) treeItem(node treeNode) listItem {
+	marker := "▸ "
+	if m.treeExpanded[node.key()] {
+		marker = "▾ "
+	}
+
+	switch {
+	case node.pane != nil:
+		title := fmt.Sprintf("      %d: %s", node.pane.Index, node.pane.Command)
+		if node.pane.Active {
+			title += " *"
+		}
+		return listItem{title: title, desc: "      " + node.pane.Path, data: node}
+
+	case node.window != nil:
+		title := fmt.Sprintf("   %s%d: %s", marker, node.window.Index, node.window.Name)
+		if node.window.Active {
+			title += " *"
+		}
+		return listItem{title: title, desc: fmt.Sprintf("     %d panes", node.window.Panes), data: node}
+	}
+
+	status := "detached"
+	if node.session.Attached {
+		status = "attached"
+	}
+	desc := fmt.Sprintf("%d windows, %s", node.session.Windows, status)
+	if len(m.servers) > 1 {
+		desc = fmt.Sprintf("[%s] %s", node.session.Server, desc)
+	}
+	return listItem{title: marker + node.session.Name, desc: "  " + desc, data: node}
+}
@@ -0,0 +1,172 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"muxyard/internal/git"
+	"muxyard/internal/tmux"
+)
+
+// highlightedRepo returns the repository under the cursor in the repo list.
+func (m MainModel) highlightedRepo() (git.Repository, bool) {
+	i := m.list.Index()
+	if i < 0 || i >= len(m.filteredRepos) {
+		return git.Repository{}, false
+	}
+	return m.filteredRepos[i], true
+}
+
+// enterAddWorktree asks for the branch of a new worktree of the repository
+// under the cursor, or of its main repository if that is a worktree.
+func (m MainModel) enterAddWorktree() (tea.Model, tea.Cmd) {
+	repo, ok := m.highlightedRepo()
+	if !ok {
+		return m, nil
+	}
+	if !repo.IsGit() {
+		m.error = fmt.Sprintf("%s is not a git repository", repo.Name)
+		return m, nil
+	}
+
+	m.worktreeMain = repo.Path
+	if repo.Kind == git.Worktree {
+		m.worktreeMain = repo.MainPath
+	}
+	m.state = worktreeBranchView
+	m.nameInput.SetValue("")
+	m.nameInput.Placeholder = "Branch"
+	m.nameInput.Focus()
+	return m, nil
+}
+
+func (m MainModel) handleWorktreeBranchKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Cancel):
+		m.nameInput.Blur()
+		m.nameInput.Placeholder = "Session name"
+		m.state = repoListView
+		return m.updateRepoList(), nil
+
+	case key.Matches(msg, m.keys.Submit):
+		branch := strings.TrimSpace(m.nameInput.Value())
+		if branch == "" {
+			return m, nil
+		}
+
+		dir, err := m.cfg.WorktreeDir(m.worktreeMain, branch)
+		if err != nil {
+			m.error = err.Error()
+			return m, nil
+		}
+		if err := git.AddWorktree(m.worktreeMain, dir, branch); err != nil {
+			m.error = fmt.Sprintf("Failed to add worktree: %v", err)
+			return m, nil
+		}
+
+		repo, ok := git.RepositoryAt(dir)
+		if !ok {
+			m.error = fmt.Sprintf("Failed to add worktree: %s is not a repository", dir)
+			return m, nil
+		}
+		// Still list the new worktree when going back from the templates
+		m.repos = insertWorktree(m.repos, repo)
+		m.repoFilterQuery = ""
+		m = m.filterRepos()
+
+		m.nameInput.Blur()
+		m.nameInput.Placeholder = "Session name"
+		m.selectedRepo = &repo
+		m.state = templateSelectView
+		m = m.loadProjectTemplate(repo.Path)
+		return m.updateTemplateList(), nil
+	}
+
+	var cmd tea.Cmd
+	m.nameInput, cmd = m.nameInput.Update(msg)
+	return m, cmd
+}
+
+// insertWorktree adds a worktree after the last repository of its group.
+func insertWorktree(repos []git.Repository, worktree git.Repository) []git.Repository {
+	i := slices.IndexFunc(repos, func(r git.Repository) bool { return r.Path == worktree.MainPath })
+	if i < 0 {
+		return append(repos, worktree)
+	}
+	for i+1 < len(repos) && repos[i+1].Kind == git.Worktree && repos[i+1].MainPath == worktree.MainPath {
+		i++
+	}
+	return slices.Insert(slices.Clone(repos), i+1, worktree)
+}
+
+// enterRemoveWorktree asks to confirm removing the worktree under the
+// cursor together with its session.
+func (m MainModel) enterRemoveWorktree() (tea.Model, tea.Cmd) {
+	repo, ok := m.highlightedRepo()
+	if !ok {
+		return m, nil
+	}
+	if repo.Kind != git.Worktree {
+		m.error = fmt.Sprintf("%s is not a worktree", repo.Name)
+		return m, nil
+	}
+
+	m.removeTarget = &repo
+	m.state = confirmRemoveWorktreeView
+	return m, nil
+}
+
+// worktreeSessions are the sessions started in a worktree, on any server.
+// Names can't tell: a session created next to a same-named one gets a
+// suffix, and other servers may have unrelated sessions of that name.
+func (m MainModel) worktreeSessions(repo git.Repository) []tmux.Session {
+	path := filepath.Clean(repo.Path)
+
+	var sessions []tmux.Session
+	for _, session := range m.sessions {
+		if session.Path != "" && filepath.Clean(session.Path) == path {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions
+}
+
+func (m MainModel) handleConfirmRemoveWorktreeKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Confirm):
+		repo := *m.removeTarget
+		m.removeTarget = nil
+		m.state = repoListView
+
+		// The worktree goes first, so that a dirty one keeps its session
+		if err := git.RemoveWorktree(repo); err != nil {
+			m.error = fmt.Sprintf("Failed to remove worktree: %v", err)
+			return m.updateRepoList(), nil
+		}
+		m.repos = slices.DeleteFunc(slices.Clone(m.repos), func(r git.Repository) bool { return r.Path == repo.Path })
+		m = m.filterRepos()
+
+		m.success = fmt.Sprintf("Removed worktree: %s", repo.Path)
+		for _, session := range m.worktreeSessions(repo) {
+			if err := m.client(session).KillSession(session.Name); err != nil {
+				m.error = fmt.Sprintf("Failed to kill session: %v", err)
+				continue
+			}
+			m.success += fmt.Sprintf("; killed session: %s", session.Name)
+			m.sessions = slices.DeleteFunc(slices.Clone(m.sessions), func(s tmux.Session) bool { return sameSession(s, session) })
+			m = m.filterSessions()
+		}
+		return m.updateRepoList(), nil
+
+	case key.Matches(msg, m.keys.Deny, m.keys.Back):
+		m.removeTarget = nil
+		m.state = repoListView
+		return m.updateRepoList(), nil
+	}
+
+	return m, nil
+}
@@ -0,0 +1,204 @@
+package zoxide
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"muxyard/internal/frecency"
+	"muxyard/internal/git"
+)
+
+// Marker marks the projects that only zoxide knows of.
+const Marker = "zoxide"
+
+// Entry is a directory in zoxide's database with its score, which weighs
+// how often it was visited by how recently.
+type Entry struct {
+	Path  string
+	Score float64
+}
+
+// Query lists the directories zoxide knows, best scored first. It asks
+// zoxide, and reads its database directly when zoxide isn't installed.
+func Query() ([]Entry, error) {
+	if _, err := exec.LookPath("zoxide"); err == nil {
+		output, err := exec.Command("zoxide", "query", "--list", "--score").Output()
+		if err == nil {
+			return parseList(string(output)), nil
+		}
+	}
+
+	path, err := DatabasePath()
+	if err != nil {
+		return nil, err
+	}
+	return ReadDatabase(path, time.Now())
+}
+
+// parseList reads the output of zoxide query --list --score, lines of a
+// score and a path such as "  12.5 /home/me/src/api".
+func parseList(output string) []Entry {
+	var entries []Entry
+	for _, line := range strings.Split(output, "\n") {
+		score, path, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseFloat(score, 64)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, Entry{Path: path, Score: value})
+	}
+	return entries
+}
+
+// DatabasePath returns where zoxide keeps its database: in $_ZO_DATA_DIR
+// if set, or else in its directory under the user's data directory.
+func DatabasePath() (string, error) {
+	if dir := os.Getenv("_ZO_DATA_DIR"); dir != "" {
+		return filepath.Join(dir, "db.zo"), nil
+	}
+
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+		if runtime.GOOS == "darwin" {
+			dataHome = filepath.Join(home, "Library", "Application Support")
+		}
+	}
+	return filepath.Join(dataHome, "zoxide", "db.zo"), nil
+}
+
+// databaseVersion is the version of the database format read here, the
+// one zoxide has written since 0.8.
+const databaseVersion = 3
+
+// ReadDatabase reads a zoxide database and scores its directories as of
+// now. Like zoxide's own listing, it leaves out directories that no longer
+// exist.
+//
+// The database is bincode: a little-endian u32 version, then a u64 count
+// of directories, each a u64-length-prefixed path, an f64 rank, and a u64
+// Unix time of the last visit.
+func ReadDatabase(path string, now time.Time) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := bytes.NewReader(data)
+	var version uint32
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if version != databaseVersion {
+		return nil, fmt.Errorf("%s has version %d, but only version %d is understood", path, version, databaseVersion)
+	}
+
+	var count uint64
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var entries []Entry
+	for i := uint64(0); i < count; i++ {
+		var length uint64
+		if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if length > uint64(r.Len()) {
+			return nil, fmt.Errorf("failed to read %s: %w", path, errors.New("path runs past the end of the file"))
+		}
+		dir := make([]byte, length)
+		if _, err := r.Read(dir); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		var rank, lastAccessed uint64
+		if err := binary.Read(r, binary.LittleEndian, &rank); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if err := binary.Read(r, binary.LittleEndian, &lastAccessed); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		if info, err := os.Stat(string(dir)); err != nil || !info.IsDir() {
+			continue
+		}
+		// muxyard ranks its own history the way zoxide does
+		visits := frecency.Entry{Rank: math.Float64frombits(rank), LastUsed: time.Unix(int64(lastAccessed), 0)}
+		entries = append(entries, Entry{Path: string(dir), Score: visits.Score(now)})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Score > entries[j].Score })
+	return entries, nil
+}
+
+// Merge adds the directories zoxide scores at least minScore to the
+// repositories. Directories inside one of the repositories add their score
+// to it rather than being listed on their own; others are listed as
+// projects, as git repositories when they are one. Only the given
+// repositories collect scores, not the directories added here, so that a
+// much visited directory high up, such as a home directory kept in git,
+// doesn't swallow the ones below it.
+func Merge(repos []git.Repository, entries []Entry, minScore float64) []git.Repository {
+	merged := append([]git.Repository(nil), repos...)
+	index := make(map[string]int, len(merged))
+	for i, repo := range merged {
+		index[repo.Path] = i
+	}
+
+	for _, entry := range entries {
+		if entry.Score < minScore {
+			continue
+		}
+		if i, ok := containing(index, entry.Path); ok {
+			merged[i].Score += entry.Score
+			continue
+		}
+
+		repo, ok := git.RepositoryAt(entry.Path)
+		if ok {
+			repo.Marker = git.GitMarker
+		} else {
+			repo = git.Repository{Name: filepath.Base(entry.Path), Path: entry.Path, Marker: Marker}
+		}
+		repo.Score = entry.Score
+		merged = append(merged, repo)
+	}
+
+	return git.SortRepositories(merged)
+}
+
+// containing finds the scanned repository that is path or holds it. The
+// search stops at the nearest git repository, so that the directories of
+// one that wasn't scanned aren't added to one further up.
+func containing(index map[string]int, path string) (int, bool) {
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if i, ok := index[dir]; ok {
+			return i, true
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return 0, false
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return 0, false
+		}
+	}
+}
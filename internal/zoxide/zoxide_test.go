@@ -0,0 +1,163 @@
+package zoxide
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"muxyard/internal/git"
+)
+
+func TestParseList(t *testing.T) {
+	output := "  48.0 /home/me/src/api\n   2.5 /home/me/My Documents\n\nnot a score\n"
+
+	entries := parseList(output)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %+v", entries)
+	}
+	if entries[0] != (Entry{Path: "/home/me/src/api", Score: 48}) {
+		t.Errorf("Unexpected first entry %+v", entries[0])
+	}
+	if entries[1].Path != "/home/me/My Documents" {
+		t.Errorf("Expected paths with spaces to be kept whole, got %q", entries[1].Path)
+	}
+}
+
+// writeDatabase writes a zoxide database of the given directories, each
+// with a rank and last visit.
+func writeDatabase(t *testing.T, path string, version uint32, dirs []string, ranks []float64, visits []time.Time) {
+	t.Helper()
+	var buf bytes.Buffer
+	write := func(v any) {
+		if err := binary.Write(&buf, binary.LittleEndian, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(version)
+	write(uint64(len(dirs)))
+	for i, dir := range dirs {
+		write(uint64(len(dir)))
+		buf.WriteString(dir)
+		write(math.Float64bits(ranks[i]))
+		write(uint64(visits[i].Unix()))
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadDatabase(t *testing.T) {
+	root := t.TempDir()
+	api, web := filepath.Join(root, "api"), filepath.Join(root, "web")
+	for _, dir := range []string{api, web} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	db := filepath.Join(root, "db.zo")
+	writeDatabase(t, db, databaseVersion,
+		[]string{api, web, filepath.Join(root, "gone")},
+		[]float64{10, 3, 50},
+		[]time.Time{now.Add(-30 * 24 * time.Hour), now.Add(-time.Minute), now})
+
+	entries, err := ReadDatabase(db, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A recent visit outweighs an old habit, and missing directories are
+	// left out
+	want := []Entry{{Path: web, Score: 12}, {Path: api, Score: 2.5}}
+	if len(entries) != len(want) || entries[0] != want[0] || entries[1] != want[1] {
+		t.Errorf("Expected %+v, got %+v", want, entries)
+	}
+
+	writeDatabase(t, db, 2, nil, nil, nil)
+	if _, err := ReadDatabase(db, now); err == nil {
+		t.Error("Expected an error for an unknown database version")
+	}
+
+	if err := os.WriteFile(db, []byte{3, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0xff}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadDatabase(db, now); err == nil {
+		t.Error("Expected an error for a truncated database")
+	}
+}
+
+func TestDatabasePath(t *testing.T) {
+	t.Setenv("_ZO_DATA_DIR", "/data/zoxide")
+	if path, _ := DatabasePath(); path != "/data/zoxide/db.zo" {
+		t.Errorf("Expected $_ZO_DATA_DIR to be used, got %s", path)
+	}
+
+	t.Setenv("_ZO_DATA_DIR", "")
+	t.Setenv("XDG_DATA_HOME", "/data")
+	if path, _ := DatabasePath(); path != "/data/zoxide/db.zo" {
+		t.Errorf("Expected $XDG_DATA_HOME to be used, got %s", path)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	// root is a home directory kept in git, with a scanned repository
+	// holding a repository of its own
+	root := t.TempDir()
+	notes := filepath.Join(root, "notes")
+	lib := filepath.Join(root, "lib")
+	api := filepath.Join(root, "src", "api")
+	dep := filepath.Join(api, "third_party", "dep")
+	for _, dir := range []string{root, lib, api, dep} {
+		if err := os.MkdirAll(filepath.Join(dir, ".git"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, ".git", "HEAD"), []byte("ref: refs/heads/main\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(notes, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	repos := []git.Repository{{Name: "api", Path: api, Marker: git.GitMarker}}
+	entries := []Entry{
+		{Path: root, Score: 40},
+		{Path: notes, Score: 20},
+		{Path: filepath.Join(api, "cmd"), Score: 8},
+		{Path: api, Score: 4},
+		{Path: lib, Score: 6},
+		{Path: filepath.Join(dep, "src"), Score: 2},
+		{Path: "/tmp", Score: 0.5},
+	}
+
+	merged := Merge(repos, entries, 1)
+	var names []string
+	for _, repo := range merged {
+		names = append(names, repo.Name)
+	}
+	if want := []string{filepath.Base(root), "api", "lib", "notes", "src"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("Expected %q, got %+v", want, merged)
+	}
+	if merged[0].Score != 40 || !merged[0].IsGit() {
+		t.Errorf("Expected the home repository to keep only its own score, got %+v", merged[0])
+	}
+	if merged[1].Score != 12 {
+		t.Errorf("Expected api to collect the scores of itself and its subdirectories, got %+v", merged[1])
+	}
+	if merged[2].Branch != "main" || !merged[2].IsGit() || merged[2].Score != 6 {
+		t.Errorf("Expected lib to be listed as a git repository, got %+v", merged[2])
+	}
+	if merged[3].Marker != Marker || merged[3].IsGit() || merged[3].Score != 20 {
+		t.Errorf("Expected notes to be listed as a zoxide directory, got %+v", merged[3])
+	}
+	if merged[4].Path != filepath.Join(dep, "src") {
+		t.Errorf("Expected a repository inside api to keep its directories from api, got %+v", merged[4])
+	}
+	if repos[0].Score != 0 {
+		t.Error("Expected the scanned repositories to be left as they were")
+	}
+}
@@ -38,8 +38,17 @@ if command -v tmux >/dev/null 2>&1; then
         exit 1
     fi
     
+    # Exercise the non-interactive subcommands
+    ./muxyard-test ls | grep -q integration-test
+    ./muxyard-test rename integration-test integration-renamed
+    if ./muxyard-test attach integration-test 2>/dev/null; then
+        echo "❌ Expected attach to a missing session to fail"
+        exit 1
+    fi
+    echo "✅ Subcommands work"
+
     # Clean up
-    tmux kill-session -t integration-test
+    ./muxyard-test kill integration-renamed
     echo "🧹 Cleaned up test session"
     
 else